@@ -0,0 +1,78 @@
+package gugo
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	AutoThrottleMinDelay = 0                // 默认自动限速最小延时
+	AutoThrottleMaxDelay = 60 * time.Second // 默认自动限速最大延时
+)
+
+// autoThrottle 自动限速：根据每个域名的下载耗时和出错情况，动态调整域名下载槽的延时和并发数，
+// 使每个域名同时在途的请求数趋近目标并发量
+type autoThrottle struct {
+	target   float64       // 目标并发量，0表示关闭自动限速
+	minDelay time.Duration // 最小延时
+	maxDelay time.Duration // 最大延时
+}
+
+func newAutoThrottle() *autoThrottle {
+	return &autoThrottle{
+		minDelay: AutoThrottleMinDelay,
+		maxDelay: AutoThrottleMaxDelay,
+	}
+}
+
+// feedback 根据一次下载的耗时和结果调整域名下载槽：
+// 1、延时趋近于 耗时/目标并发量，出错时延时只增不减
+// 2、成功时并发数逐步恢复到目标并发量，出错时并发数减半
+// 3、延时不低于域名设置的限速间隔，并发数不超过域名设置的最大并发数
+func (a *autoThrottle) feedback(sl *slot, latency time.Duration, failed bool) {
+	if a.target <= 0 || sl == nil {
+		return
+	}
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	target := time.Duration(float64(latency) / a.target)
+	delay := (sl.delay + target) / 2
+	if delay < target {
+		delay = target
+	}
+	if delay < a.minDelay {
+		delay = a.minDelay
+	}
+	if delay > a.maxDelay {
+		delay = a.maxDelay
+	}
+	limit := uint32(math.Ceil(a.target))
+	if failed {
+		if delay < sl.delay {
+			delay = sl.delay
+		}
+		if limit = sl.limit / 2; limit == 0 {
+			limit = 1
+		}
+	} else if sl.limit > 0 && sl.limit < limit {
+		limit = sl.limit + 1
+	}
+	if delay < sl.minDelay {
+		delay = sl.minDelay
+	}
+	if sl.maxLimit > 0 && limit > sl.maxLimit {
+		limit = sl.maxLimit
+	}
+	sl.delay, sl.limit = delay, limit
+	sl.signal()
+}
+
+// SetAutoThrottle 开启自动限速并设置每个域名的目标并发量，0表示关闭自动限速
+func (a *autoThrottle) SetAutoThrottle(target float64) {
+	a.target = target
+}
+
+// SetAutoThrottleDelay 设置自动限速的最小延时和最大延时
+func (a *autoThrottle) SetAutoThrottleDelay(min, max time.Duration) {
+	a.minDelay, a.maxDelay = min, max
+}
@@ -0,0 +1,120 @@
+package gugo
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	BreakerThreshold = 0                // 默认熔断阈值：域名连续失败的次数，0表示关闭熔断
+	BreakerCooldown  = 30 * time.Second // 默认熔断冷却时间
+)
+
+type breakerState uint8
+
+const (
+	breakerClosed   breakerState = iota // 关闭：请求正常下载
+	breakerOpen                         // 打开：请求被暂缓或直接失败
+	breakerHalfOpen                     // 半开：只放行一个试探请求
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// breaker 域名熔断器，保存在域名下载槽中，由下载器根据下载结果切换状态
+type breaker struct {
+	state    breakerState
+	failures uint32    // 连续失败次数
+	until    time.Time // 打开状态持续到的时间
+	park     bool      // 打开期间暂缓该域名的请求，否则请求直接失败
+	probeAt  time.Time // 半开状态下试探请求的出发时间，零值表示没有试探请求
+	cooldown time.Duration
+}
+
+// blocked 熔断器是否阻止请求出队：0表示放行，大于0表示需要等待的时间，小于0表示需要等待信号，
+// 调用方需持有下载槽的锁
+func (s *slot) blocked() time.Duration {
+	b := &s.breaker
+	switch b.state {
+	case breakerOpen:
+		wait := time.Until(b.until)
+		if wait <= 0 {
+			s.transit(breakerHalfOpen)
+			return 0
+		}
+		if b.park {
+			return wait
+		}
+	case breakerHalfOpen:
+		// 试探请求迟迟没有结果(如被中间件丢弃)时，允许再次试探
+		if !b.probeAt.IsZero() && time.Since(b.probeAt) < b.cooldown {
+			return -1
+		}
+	}
+	return 0
+}
+
+// probe 半开状态下出队的请求作为试探请求，调用方需持有下载槽的锁
+func (s *slot) probe() {
+	if s.breaker.state == breakerHalfOpen {
+		s.breaker.probeAt = time.Now()
+	}
+}
+
+// isOpen 熔断器是否处于打开状态
+func (s *slot) isOpen() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.breaker.state == breakerOpen && time.Now().Before(s.breaker.until)
+}
+
+// transit 切换熔断器状态并记录日志，调用方需持有下载槽的锁
+func (s *slot) transit(state breakerState) {
+	log.Printf("%s circuit breaker is %s\n", s.host, state)
+	s.breaker.state = state
+	s.breaker.probeAt = time.Time{}
+}
+
+// trip 根据一次下载的结果更新域名熔断器：
+// 关闭状态下连续失败达到阈值、或半开状态下试探请求失败时打开，成功时关闭
+func (d *downloader) trip(sl *slot, failed bool) {
+	if d.breakerThreshold == 0 || sl == nil {
+		return
+	}
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	b := &sl.breaker
+	if !failed {
+		b.failures = 0
+		if b.state != breakerClosed {
+			sl.transit(breakerClosed)
+			sl.signal()
+		}
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.state == breakerClosed && b.failures >= d.breakerThreshold {
+		b.until = time.Now().Add(d.breakerCooldown)
+		b.park = !d.breakerFailFast
+		b.cooldown = d.breakerCooldown
+		sl.transit(breakerOpen)
+		d.IncrTripCount()
+	}
+}
+
+// SetCircuitBreaker 设置域名熔断器：连续失败threshold次后打开，cooldown后半开试探，threshold为0表示关闭熔断
+func (d *downloader) SetCircuitBreaker(threshold uint32, cooldown time.Duration) {
+	d.breakerThreshold, d.breakerCooldown = threshold, cooldown
+}
+
+// SetCircuitBreakerFailFast 设置熔断器打开期间该域名的请求直接失败，默认暂缓到熔断器半开
+func (d *downloader) SetCircuitBreakerFailFast(failFast bool) {
+	d.breakerFailFast = failFast
+}
@@ -0,0 +1,127 @@
+package gugo
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/xiaogogonuo/gugo/pkg/breakpoint"
+	"log"
+	"strings"
+)
+
+// Checkpoint 保存断点到目录：尚未处理完毕的请求、去重过滤器、重试计数、统计信息和会话 Cookie
+func (e *engine) Checkpoint(dir string) error {
+	cp := &breakpoint.Checkpoint{
+		Retry: make(map[string]uint32),
+		Stats: map[string]map[string]uint64{
+			"scheduler":  e.scheduler.snapshot(),
+			"downloader": e.downloader.snapshot(),
+			"spider":     e.spider.snapshot(),
+		},
+	}
+	for _, record := range e.pendingRecords() {
+		rr := *record
+		rr.filterMeta()
+		b, err := json.Marshal(&rr)
+		if err != nil {
+			return err
+		}
+		cp.Requests = append(cp.Requests, b)
+	}
+	var filter bytes.Buffer
+	e.smu.Lock()
+	_, err := e.filter.WriteTo(&filter)
+	e.smu.Unlock()
+	if err != nil {
+		return err
+	}
+	cp.Filter = filter.Bytes()
+	if e.cookieJars != nil {
+		if cp.Cookies, err = e.cookieJars.marshal(); err != nil {
+			return err
+		}
+	}
+	e.dmu.Lock()
+	for k, v := range e.retryMonitor {
+		cp.Retry[hex.EncodeToString([]byte(k))] = v
+	}
+	e.dmu.Unlock()
+	return breakpoint.Save(dir, cp)
+}
+
+// SetCheckpoint 设置断点目录，收到 SIGINT、SIGTERM 信号时保存断点并停止爬虫
+func (e *engine) SetCheckpoint(dir string) {
+	e.checkpoint = dir
+}
+
+// watchCheckpoint 监听中断信号
+func (e *engine) watchCheckpoint() {
+	if e.checkpoint == "" {
+		return
+	}
+	breakpoint.Watch(func() {
+		if err := e.Checkpoint(e.checkpoint); err != nil {
+			log.Printf("save checkpoint to %s failed: %v\n", e.checkpoint, err)
+		} else {
+			log.Printf("checkpoint is saved to %s\n", e.checkpoint)
+		}
+		cancel()
+	})
+}
+
+// decodeRequest 断点中的请求反序列化
+func decodeRequest(b []byte, parsers map[string]Parser) (*Request, error) {
+	record := &RequestRecord{}
+	if err := json.Unmarshal(b, record); err != nil {
+		return nil, err
+	}
+	return record.Request(parsers[record.Callback])
+}
+
+// Resume 从断点目录恢复爬虫，需要在 GooGol 之前调用。
+// 未完成请求的解析器优先按函数名从 parsers 中匹配，其次按名字查找注册的解析器。
+func (e *engine) Resume(dir string, parsers ...Parser) error {
+	cp, err := breakpoint.Load(dir)
+	if err != nil {
+		return err
+	}
+	if len(cp.Filter) > 0 {
+		e.smu.Lock()
+		_, err = e.filter.ReadFrom(bytes.NewReader(cp.Filter))
+		e.smu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	e.dmu.Lock()
+	for k, v := range cp.Retry {
+		fingerprint, err := hex.DecodeString(k)
+		if err != nil {
+			continue
+		}
+		e.retryMonitor[string(fingerprint)] = v
+	}
+	e.dmu.Unlock()
+	if len(cp.Cookies) > 0 && e.cookieJars != nil {
+		if err = e.cookieJars.unmarshal(cp.Cookies); err != nil {
+			return err
+		}
+	}
+	e.scheduler.restore(cp.Stats["scheduler"])
+	e.downloader.restore(cp.Stats["downloader"])
+	e.spider.restore(cp.Stats["spider"])
+	named := namedParsers(parsers)
+	var skipped []string
+	for _, b := range cp.Requests {
+		r, err := decodeRequest(b, named)
+		if err != nil {
+			skipped = append(skipped, err.Error())
+			continue
+		}
+		e.enqueue(r)
+	}
+	if len(skipped) > 0 {
+		log.Printf("%d requests are skipped when resuming:\n%s\n", len(skipped), strings.Join(skipped, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package gugo
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func checkpointParser(*Response) {}
+
+// TestCheckpointInFlight 请求正在下载时保存断点，断点中的请求与被接受时一致
+func TestCheckpointInFlight(t *testing.T) {
+	e := newEngine()
+	if err := e.SetProxyPool(ProxyRoundRobin, "http://127.0.0.1:1"); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := http.NewRequest(http.MethodPost, "http://example.com/search", strings.NewReader("q=gugo"))
+	meta := map[string]interface{}{"page": 1, "client": func() {}}
+	req := NewRequest(r, checkpointParser, meta, Priority(3))
+	e.ask(req)
+	dir := filepath.Join(t.TempDir(), "checkpoint")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// 模拟分发下载：复制请求并读取请求体、把代理放入请求上下文
+		for i := 0; i < 100; i++ {
+			e.prepare(req)
+			_, _ = e.withProxy(req)
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if err := e.Checkpoint(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	resumed := newEngine()
+	if err := resumed.Resume(dir, checkpointParser); err != nil {
+		t.Fatal(err)
+	}
+	records := resumed.pendingRecords()
+	if len(records) != 1 {
+		t.Fatalf("resumed requests = %d, want 1", len(records))
+	}
+	rr := records[0]
+	if rr.Method != http.MethodPost || rr.URL != "http://example.com/search" || string(rr.Body) != "q=gugo" {
+		t.Errorf("resumed request = %s %s %q", rr.Method, rr.URL, rr.Body)
+	}
+	if rr.Priority != 3 || rr.Meta["page"] != float64(1) || len(rr.Meta) != 1 {
+		t.Errorf("resumed priority, meta = %d, %v", rr.Priority, rr.Meta)
+	}
+	if rr.Callback != parserName(checkpointParser) {
+		t.Errorf("resumed callback = %q", rr.Callback)
+	}
+}
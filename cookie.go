@@ -0,0 +1,172 @@
+package gugo
+
+import (
+	"encoding/json"
+	"golang.org/x/net/publicsuffix"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// Session 请求使用的会话，不同会话的 Cookie 相互隔离，默认会话的名字为空字符串，
+// 可用于同时进行登录和匿名爬取，或同时使用多个账号
+func Session(key string) RequestOption {
+	return func(r *Request) {
+		r.session = key
+	}
+}
+
+// Session 请求使用的会话
+func (r *Request) Session() string {
+	return r.session
+}
+
+// cookieRecord 一次 Set-Cookie 的记录，用于导出 Cookie
+type cookieRecord struct {
+	URL    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+// sessionJar 单个会话的 Cookie 容器，记录收到的 Cookie 以便导出，导入时按顺序重放
+type sessionJar struct {
+	jar     *cookiejar.Jar
+	mu      sync.Mutex
+	seq     []string                // 记录的先后顺序
+	records map[string]cookieRecord // 同一域名、路径、名字的 Cookie 只保留最新的记录
+}
+
+func newSessionJar() *sessionJar {
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	return &sessionJar{jar: jar, records: make(map[string]cookieRecord)}
+}
+
+func (s *sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	s.jar.SetCookies(u, cookies)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, c := range cookies {
+		c := *c
+		// MaxAge 是相对时间，转换为绝对时间后导入时才不会延长有效期
+		if c.MaxAge > 0 {
+			c.Expires, c.MaxAge = now.Add(time.Duration(c.MaxAge)*time.Second), 0
+		}
+		c.Raw = ""
+		key := u.Host + ";" + c.Domain + ";" + c.Path + ";" + c.Name
+		if _, ok := s.records[key]; !ok {
+			s.seq = append(s.seq, key)
+		}
+		s.records[key] = cookieRecord{URL: u.Scheme + "://" + u.Host + u.Path, Cookie: &c}
+	}
+}
+
+func (s *sessionJar) Cookies(u *url.URL) []*http.Cookie {
+	return s.jar.Cookies(u)
+}
+
+// export 导出仍然有效的 Cookie 记录
+func (s *sessionJar) export() []cookieRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var records []cookieRecord
+	for _, key := range s.seq {
+		r := s.records[key]
+		if r.Cookie.MaxAge < 0 || !r.Cookie.Expires.IsZero() && r.Cookie.Expires.Before(now) {
+			continue
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+// cookieJars 按会话名字区分的 Cookie 容器
+type cookieJars struct {
+	mu   sync.Mutex
+	jars map[string]*sessionJar
+}
+
+func newCookieJars() *cookieJars {
+	return &cookieJars{jars: make(map[string]*sessionJar)}
+}
+
+// jar 会话对应的 Cookie 容器，不存在时创建
+func (cj *cookieJars) jar(session string) *sessionJar {
+	cj.mu.Lock()
+	defer cj.mu.Unlock()
+	jar, ok := cj.jars[session]
+	if !ok {
+		jar = newSessionJar()
+		cj.jars[session] = jar
+	}
+	return jar
+}
+
+// marshal 所有会话的 Cookie 序列化为JSON
+func (cj *cookieJars) marshal() ([]byte, error) {
+	cj.mu.Lock()
+	sessions := make(map[string][]cookieRecord, len(cj.jars))
+	for session, jar := range cj.jars {
+		if records := jar.export(); len(records) > 0 {
+			sessions[session] = records
+		}
+	}
+	cj.mu.Unlock()
+	return json.MarshalIndent(sessions, "", "  ")
+}
+
+// unmarshal 从JSON导入 Cookie，与已有的 Cookie 合并
+func (cj *cookieJars) unmarshal(data []byte) error {
+	sessions := make(map[string][]cookieRecord)
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return err
+	}
+	for session, records := range sessions {
+		jar := cj.jar(session)
+		for _, r := range records {
+			u, err := url.Parse(r.URL)
+			if err != nil || r.Cookie == nil {
+				continue
+			}
+			jar.SetCookies(u, []*http.Cookie{r.Cookie})
+		}
+	}
+	return nil
+}
+
+// SetCookieJar 设置是否使用内置的 Cookie 容器，默认使用，
+// 客户端(默认客户端或meta中的客户端)自带 Cookie 容器时使用客户端的容器
+func (d *downloader) SetCookieJar(enabled bool) {
+	if !enabled {
+		d.cookieJars = nil
+	} else if d.cookieJars == nil {
+		d.cookieJars = newCookieJars()
+	}
+}
+
+// ExportCookies 导出所有会话的 Cookie 到文件
+func (d *downloader) ExportCookies(path string) error {
+	if d.cookieJars == nil {
+		return nil
+	}
+	data, err := d.cookieJars.marshal()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// ImportCookies 从文件导入 Cookie，与已有的 Cookie 合并
+func (d *downloader) ImportCookies(path string) error {
+	if d.cookieJars == nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return d.cookieJars.unmarshal(data)
+}
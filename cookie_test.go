@@ -0,0 +1,71 @@
+package gugo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCookieSessions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			user := r.URL.Query().Get("user")
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: user, Path: "/", MaxAge: 3600})
+			http.SetCookie(w, &http.Cookie{Name: "flash", Value: "1", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "old", Value: "1", Path: "/", MaxAge: -1})
+		case "/whoami":
+			var names []string
+			for _, c := range r.Cookies() {
+				names = append(names, c.Name+"="+c.Value)
+			}
+			_, _ = w.Write([]byte(strings.Join(names, ";")))
+		}
+	}))
+	defer srv.Close()
+	get := func(d *downloader, path, session string) string {
+		r, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		res, err := d.do(NewRequest(r, nil, nil, Session(session)), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(res.Body())
+	}
+	d := newDownloader()
+	get(d, "/login?user=alice", "alice")
+	get(d, "/login?user=bob", "bob")
+	tests := []struct {
+		session string
+		want    string
+	}{
+		{"alice", "sid=alice;flash=1"},
+		{"bob", "sid=bob;flash=1"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := get(d, "/whoami", tt.session); got != tt.want {
+			t.Errorf("session %q cookies = %q, want %q", tt.session, got, tt.want)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	if err := d.ExportCookies(path); err != nil {
+		t.Fatal(err)
+	}
+	imported := newDownloader()
+	if err := imported.ImportCookies(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		if got := get(imported, "/whoami", tt.session); got != tt.want {
+			t.Errorf("imported session %q cookies = %q, want %q", tt.session, got, tt.want)
+		}
+	}
+	// 关闭内置容器后不保存 Cookie
+	d.SetCookieJar(false)
+	get(d, "/login?user=carol", "carol")
+	if got := get(d, "/whoami", "carol"); got != "" {
+		t.Errorf("cookies without a jar = %q, want none", got)
+	}
+}
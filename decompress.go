@@ -0,0 +1,128 @@
+package gugo
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// AcceptEncoding 下载器支持解压的内容编码，请求没有设置 Accept-Encoding 时使用
+const AcceptEncoding = "gzip, deflate, br, zstd"
+
+// decoders 内容编码对应的解压器
+var decoders = map[string]func(io.Reader) (io.ReadCloser, error){
+	"gzip":    newGzipReader,
+	"x-gzip":  newGzipReader,
+	"deflate": newDeflateReader,
+	"br": func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(brotli.NewReader(r)), nil
+	},
+	"zstd": func(r io.Reader) (io.ReadCloser, error) {
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	},
+}
+
+func newGzipReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// newDeflateReader HTTP的 deflate 通常是 zlib 格式，也有服务器发送不带 zlib 头的原始 deflate 数据
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && header[0]&0x0F == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// lazyDecoder 第一次读取时才创建解压器，避免空响应体在下载时出错
+type lazyDecoder struct {
+	src io.Reader
+	new func(io.Reader) (io.ReadCloser, error)
+	rc  io.ReadCloser
+	err error
+}
+
+func (l *lazyDecoder) Read(p []byte) (int, error) {
+	if l.rc == nil && l.err == nil {
+		l.rc, l.err = l.new(l.src)
+	}
+	if l.err != nil {
+		return 0, l.err
+	}
+	return l.rc.Read(p)
+}
+
+func (l *lazyDecoder) Close() error {
+	if l.rc != nil {
+		return l.rc.Close()
+	}
+	return nil
+}
+
+// decodedBody 解压后的响应体，关闭时同时关闭所有解压器和原始响应体
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	var err error
+	for _, c := range b.closers {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// decompress 按响应头 Content-Encoding 解压响应体，多个编码按相反的顺序解压，
+// 解压后的大小受响应体最大字节数限制，含有不支持的编码时不解压
+func (d *downloader) decompress(res *http.Response) {
+	if !d.decompression {
+		return
+	}
+	header := res.Header.Get("Content-Encoding")
+	if header == "" {
+		return
+	}
+	encodings := strings.Split(header, ",")
+	for i, enc := range encodings {
+		enc = strings.ToLower(strings.TrimSpace(enc))
+		if _, ok := decoders[enc]; !ok && enc != "identity" {
+			return
+		}
+		encodings[i] = enc
+	}
+	var r io.Reader = res.Body
+	closers := []io.Closer{res.Body}
+	for i := len(encodings) - 1; i >= 0; i-- {
+		if encodings[i] == "identity" {
+			continue
+		}
+		ld := &lazyDecoder{src: r, new: decoders[encodings[i]]}
+		r = ld
+		closers = append([]io.Closer{ld}, closers...)
+	}
+	res.Body = &decodedBody{Reader: r, closers: closers}
+	res.Header.Del("Content-Encoding")
+	res.Header.Del("Content-Length")
+	res.ContentLength = -1
+	res.Uncompressed = true
+}
+
+// SetDecompression 设置是否由下载器声明并解压 gzip、deflate、br、zstd 编码的响应体，默认开启
+func (d *downloader) SetDecompression(enabled bool) {
+	d.decompression = enabled
+}
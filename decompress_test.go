@@ -0,0 +1,129 @@
+package gugo
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// encoders 内容编码对应的压缩函数
+var encoders = map[string]func([]byte) []byte{
+	"gzip": func(b []byte) []byte {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, _ = w.Write(b)
+		_ = w.Close()
+		return buf.Bytes()
+	},
+	"deflate": func(b []byte) []byte {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		_, _ = w.Write(b)
+		_ = w.Close()
+		return buf.Bytes()
+	},
+	"raw-deflate": func(b []byte) []byte {
+		var buf bytes.Buffer
+		w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+		_, _ = w.Write(b)
+		_ = w.Close()
+		return buf.Bytes()
+	},
+	"br": func(b []byte) []byte {
+		var buf bytes.Buffer
+		w := brotli.NewWriter(&buf)
+		_, _ = w.Write(b)
+		_ = w.Close()
+		return buf.Bytes()
+	},
+	"zstd": func(b []byte) []byte {
+		w, _ := zstd.NewWriter(nil)
+		defer w.Close()
+		return w.EncodeAll(b, nil)
+	},
+	"identity": func(b []byte) []byte { return b },
+}
+
+func TestDecompress(t *testing.T) {
+	plain := []byte(strings.Repeat("gugo decompress ", 100))
+	tests := []struct {
+		header string   // 响应头 Content-Encoding
+		steps  []string // 压缩的顺序
+	}{
+		{"gzip", []string{"gzip"}},
+		{"x-gzip", []string{"gzip"}},
+		{"deflate", []string{"deflate"}},
+		{"deflate", []string{"raw-deflate"}},
+		{"br", []string{"br"}},
+		{"zstd", []string{"zstd"}},
+		{"br, gzip", []string{"br", "gzip"}},
+		{"ZSTD , identity, deflate", []string{"zstd", "deflate"}},
+	}
+	d := newDownloader()
+	for _, tt := range tests {
+		body := plain
+		for _, step := range tt.steps {
+			body = encoders[step](body)
+		}
+		res := &http.Response{
+			Header:        http.Header{"Content-Encoding": {tt.header}, "Content-Length": {strconv.Itoa(len(body))}},
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+		}
+		d.decompress(res)
+		got, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Errorf("%s %v: %v", tt.header, tt.steps, err)
+			continue
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("%s %v: body is not decompressed", tt.header, tt.steps)
+		}
+		if res.Header.Get("Content-Encoding") != "" || res.ContentLength != -1 || !res.Uncompressed {
+			t.Errorf("%s %v: headers are not updated", tt.header, tt.steps)
+		}
+		_ = res.Body.Close()
+	}
+}
+
+func TestDecompressUnsupported(t *testing.T) {
+	d := newDownloader()
+	body := encoders["gzip"]([]byte("gugo"))
+	res := &http.Response{
+		Header: http.Header{"Content-Encoding": {"gzip, compress"}},
+		Body:   io.NopCloser(bytes.NewReader(body)),
+	}
+	d.decompress(res)
+	if got, _ := io.ReadAll(res.Body); !bytes.Equal(got, body) || res.Header.Get("Content-Encoding") == "" {
+		t.Error("body with an unsupported encoding should be left as is")
+	}
+	d.SetDecompression(false)
+	res = &http.Response{
+		Header: http.Header{"Content-Encoding": {"gzip"}},
+		Body:   io.NopCloser(bytes.NewReader(body)),
+	}
+	d.decompress(res)
+	if got, _ := io.ReadAll(res.Body); !bytes.Equal(got, body) {
+		t.Error("body should be left as is when decompression is disabled")
+	}
+}
+
+func TestDecompressEmptyBody(t *testing.T) {
+	d := newDownloader()
+	res := &http.Response{
+		Header: http.Header{"Content-Encoding": {"gzip"}},
+		Body:   http.NoBody,
+	}
+	d.decompress(res)
+	if err := res.Body.Close(); err != nil {
+		t.Errorf("closing an unread empty body: %v", err)
+	}
+}
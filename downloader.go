@@ -1,6 +1,7 @@
 package gugo
 
 import (
+	"errors"
 	"log"
 	"net"
 	"net/http"
@@ -12,90 +13,220 @@ const (
 	MaxRetry         = 5                // 默认最大下载重试次数
 	ConnectTimeout   = 10 * time.Second // 默认客户端连接超时时间
 	ReadWriteTimeout = 10 * time.Second // 默认客户端读写超时时间
+	DownloadTimeout  = time.Duration(0) // 默认请求下载的总时间，0表示不限制
 )
 
 var (
 	// RetryHTTPCode 默认请求失败时允许重新下载的状态码
-	RetryHTTPCode = []int{500, 502, 503, 504, 408}
+	RetryHTTPCode = []int{500, 502, 503, 504, 408, 429}
 )
 
 type downloader struct {
-	dmu              sync.RWMutex      // 读写锁
-	maxRetry         uint32            // 最大下载重试次数
-	connectTimeout   time.Duration     // 客户端连接超时时间
-	readWriteTimeout time.Duration     // 客户端读写超时时间
-	retryHTTPCode    []int             // 下载失败重试状态码
-	retryMonitor     map[string]uint32 // 下载失败重试监控器
+	dmu              sync.RWMutex           // 读写锁
+	maxRetry         uint32                 // 最大下载重试次数
+	connectTimeout   time.Duration          // 客户端连接超时时间
+	readWriteTimeout time.Duration          // 客户端读写超时时间
+	downloadTimeout  time.Duration          // 请求下载的总时间
+	retryHTTPCode    []int                  // 下载失败重试状态码
+	retryMonitor     map[string]uint32      // 下载失败重试监控器
+	retryBackoff     time.Duration          // 重试退避基础时间
+	maxRetryBackoff  time.Duration          // 重试退避最大时间
+	retryJitter      float64                // 重试退避随机抖动比例
+	breakerThreshold uint32                 // 熔断阈值
+	breakerCooldown  time.Duration          // 熔断冷却时间
+	breakerFailFast  bool                   // 熔断期间请求直接失败
+	middlewares      []DownloaderMiddleware // 下载器中间件
+	httpCache        *httpCache             // 磁盘HTTP缓存，nil表示不缓存
+	proxyPool        *proxyPool             // 代理池，nil表示不使用代理
+	defaultHeader    http.Header            // 默认请求头
+	headerProfiles   *headerProfiles        // 请求头模板池，nil表示不使用模板
+	cookieJars       *cookieJars            // 按会话区分的 Cookie 容器，nil表示不使用
+	dialer           *net.Dialer            // 共享连接池的拨号器
+	transport        *http.Transport        // 共享连接池，客户端没有设置 Transport 时使用
+	maxResponseSize  int64                  // 响应体最大字节数
+	warnResponseSize int64                  // 响应体告警字节数
+	defaultEncoding  string                 // 无法判断响应体编码时使用的默认编码
+	decompression    bool                   // 是否声明并解压压缩的响应体
 	*http.Client
 	*module
+	*autoThrottle
 }
 
 func newDownloader() *downloader {
+	dialer := &net.Dialer{Timeout: ConnectTimeout, KeepAlive: 30 * time.Second}
 	return &downloader{
 		maxRetry:         MaxRetry,
 		connectTimeout:   ConnectTimeout,
 		readWriteTimeout: ReadWriteTimeout,
+		downloadTimeout:  DownloadTimeout,
 		retryHTTPCode:    RetryHTTPCode,
 		retryMonitor:     make(map[string]uint32),
+		retryBackoff:     RetryBackoff,
+		maxRetryBackoff:  MaxRetryBackoff,
+		retryJitter:      RetryJitter,
+		breakerThreshold: BreakerThreshold,
+		breakerCooldown:  BreakerCooldown,
+		defaultHeader:    DefaultHeader,
+		cookieJars:       newCookieJars(),
+		dialer:           dialer,
+		transport:        newTransport(dialer),
+		maxResponseSize:  MaxResponseSize,
+		warnResponseSize: WarnResponseSize,
+		decompression:    true,
 		Client:           &http.Client{},
 		module:           &module{},
+		autoThrottle:     newAutoThrottle(),
 	}
 }
 
-// download 统计项：
+// download 返回需要重新放入请求队列的请求或需要交给爬虫解析的响应，都为nil表示请求处理完毕，
+// 请求最终下载失败时调用请求的错误回调，统计项：
 // 1、下载器正在处理的数量
 // 2、客户端请求失败的数量
 // 3、客户端请求成功的数量
-func (d *downloader) download(req *request, concurrent chan struct{}, reqBuf chan *request, resBuf chan *Response) {
+// 4、请求被中间件丢弃或过滤的数量
+func (d *downloader) download(req *Request, sl *slot, concurrent chan struct{}) (*Request, *Response) {
 	defer func() { <-concurrent }()
 	d.IncrHandlingNumber()
 	defer d.DecrHandlingNumber()
-	d.Client.Transport = &http.Transport{
-		Dial: timeoutDialer(d.connectTimeout, d.readWriteTimeout),
-	}
-	d.coverClient(req)
-	res, err := d.Client.Do(req.Request)
-	if err != nil || d.isRetryHTTPCode(res.StatusCode) {
-		log.Println(err)
-		if d.isNeedRetry(req) {
-			go func() { reqBuf <- req }()
-			return
+	r, res, err := d.handle(req, sl)
+	switch {
+	case r != nil:
+		return r, nil
+	case err != nil:
+		if errors.Is(err, ErrFiltered) {
+			d.IncrInterceptCount()
+		} else {
+			log.Println(err)
+			d.IncrFailedCount()
 		}
+		req.fail(err)
+	case res != nil && errors.Is(res.bodyErr, ErrTooLarge):
+		// 中间件读取响应体时超过大小限制
+		log.Println(res.bodyErr)
 		d.IncrFailedCount()
-		return
+		req.fail(res.bodyErr)
+	case res != nil:
+		if res.Request == nil {
+			res.Request = req
+		}
+		res.abort = d.onAbort(req)
+		res.defaultEncoding = d.defaultEncoding
+		d.IncrCompletedCount()
+		return nil, res
+	default:
+		d.IncrInterceptCount()
 	}
-	d.IncrCompletedCount()
-	go func() { resBuf <- &Response{res, req} }()
+	return nil, nil
 }
 
-// coverClient 覆盖默认客户端
-func (d *downloader) coverClient(r *request) {
-	if r.meta == nil {
-		return
+// handle 请求经过中间件链下载，返回值约定同 DownloaderMiddleware
+func (d *downloader) handle(req *Request, sl *slot) (*Request, *Response, error) {
+	r, res, err := d.processRequest(req)
+	switch {
+	case r == req:
+		if sl != nil && sl.isOpen() {
+			err = &RequestError{Kind: ErrCircuitOpen, URL: req.URL()}
+			break
+		}
+		res, err = d.fetch(req, sl)
+		if err == nil {
+			break
+		}
+		if !d.retryable(err) {
+			break
+		}
+		if n, ok := d.isNeedRetry(req); ok {
+			req.retryDelay = d.backoff(n, err)
+			log.Printf("%v, retry %d after %s\n", err, n, req.retryDelay)
+			return req, nil, nil
+		}
+	case r != nil || res == nil && err == nil:
+		res.discard()
+		return r, nil, nil
+	}
+	if err != nil {
+		if r, res, err = d.processError(req, err); res == nil {
+			return r, nil, err
+		}
+	}
+	return d.processResponse(req, res)
+}
+
+// do 客户端发起请求，重试状态码视为请求失败，统计项：
+// 1、请求下载的次数
+// 2、请求下载出错的次数
+// 3、请求下载的耗时
+func (d *downloader) do(req *Request, sl *slot) (*Response, error) {
+	client := *d.client(req)
+	if client.Jar == nil && d.cookieJars != nil {
+		client.Jar = d.cookieJars.jar(req.session)
+	}
+	// 客户端自己的 Transport 不会读取请求上下文中的代理，此时不使用代理池
+	var p *proxy
+	if client.Transport == nil {
+		client.Transport = d.transport
+		var err error
+		if p, err = d.withProxy(req); err != nil {
+			return nil, err
+		}
+	}
+	r, dl := d.withDeadline(req, d.prepare(req))
+	start := time.Now()
+	res, err := client.Do(r)
+	err = dl.done(res, err)
+	latency := time.Since(start)
+	failed := err != nil || d.isRetryHTTPCode(res.StatusCode)
+	d.IncrDownloadCount()
+	d.AddLatency(latency)
+	if failed {
+		d.IncrErrorCount()
+	}
+	d.feedback(sl, latency, failed)
+	d.trip(sl, failed)
+	if d.proxyPool != nil {
+		d.proxyPool.feedback(p, err != nil)
+	}
+	if err != nil {
+		return nil, networkError(req, err)
+	}
+	if d.isRetryHTTPCode(res.StatusCode) {
+		_ = res.Body.Close()
+		return nil, &RequestError{
+			Kind:       ErrRetryHTTPCode,
+			URL:        req.URL(),
+			StatusCode: res.StatusCode,
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After")),
+		}
+	}
+	d.decompress(res)
+	if err = d.limit(req, res); err != nil {
+		return nil, err
 	}
+	return &Response{Response: res, Request: req}, nil
+}
+
+// client 请求使用的客户端：meta中有 *http.Client 时使用该客户端，否则使用默认客户端，不修改默认客户端
+func (d *downloader) client(r *Request) *http.Client {
 	for _, v := range r.meta {
 		if client, ok := v.(*http.Client); ok {
-			d.Client = client
-			return
+			return client
 		}
 	}
+	return d.Client
 }
 
-// isNeedRetry 客户端请求错误是否需要重试
-func (d *downloader) isNeedRetry(r *request) bool {
-	d.dmu.RLock()
-	defer d.dmu.RUnlock()
-	var c uint32
-	c, ok := d.retryMonitor[r.FingerPrintS()]
-	if !ok {
-		d.retryMonitor[r.FingerPrintS()] = 1
-		return true
-	}
-	if c >= d.maxRetry-1 {
-		return false
+// isNeedRetry 客户端请求错误是否需要重试，需要时同时返回这是第几次重试
+func (d *downloader) isNeedRetry(r *Request) (uint32, bool) {
+	d.dmu.Lock()
+	defer d.dmu.Unlock()
+	fingerprint := r.FingerPrintS()
+	c, ok := d.retryMonitor[fingerprint]
+	if ok && c >= d.maxRetry-1 {
+		return c, false
 	}
-	d.retryMonitor[r.FingerPrintS()]++
-	return true
+	d.retryMonitor[fingerprint] = c + 1
+	return c + 1, true
 }
 
 // isRetryHTTPCode 是否是失败重试状态码
@@ -122,7 +253,7 @@ func (d *downloader) SetRetryHTTPCode(httpCode ...int) {
 	}
 }
 
-// SetClient 设置客户端
+// SetClient 设置客户端，客户端没有设置 Transport 时使用下载器共享的连接池
 func (d *downloader) SetClient(client *http.Client) {
 	d.Client = client
 }
@@ -130,20 +261,15 @@ func (d *downloader) SetClient(client *http.Client) {
 // SetConnectTimeout 设置客户端连接超时时间
 func (d *downloader) SetConnectTimeout(timeout time.Duration) {
 	d.connectTimeout = timeout
+	d.dialer.Timeout = timeout
 }
 
-// SetReadWriteTimeout 设置客户端读写超时时间
+// SetReadWriteTimeout 设置客户端读写超时时间：等待响应头、每次读取响应体的最长时间
 func (d *downloader) SetReadWriteTimeout(timeout time.Duration) {
 	d.readWriteTimeout = timeout
 }
 
-func timeoutDialer(connTimeout time.Duration, rwTimeout time.Duration) func(net, addr string) (c net.Conn, err error) {
-	return func(n, addr string) (net.Conn, error) {
-		conn, err := net.DialTimeout(n, addr, connTimeout)
-		if err != nil {
-			return nil, err
-		}
-		err = conn.SetDeadline(time.Now().Add(rwTimeout))
-		return conn, nil
-	}
+// SetDownloadTimeout 设置请求下载的总时间：从发出请求到读完响应体的最长时间，包括响应等待解析的时间，0表示不限制
+func (d *downloader) SetDownloadTimeout(timeout time.Duration) {
+	d.downloadTimeout = timeout
 }
@@ -0,0 +1,116 @@
+package gugo
+
+import (
+	"bytes"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"log"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// utf8BOM UTF-8 的字节顺序标记
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Encoding 响应体的字符编码，如 utf-8、gbk、big5，依次根据 BOM、响应头 Content-Type、
+// <meta charset> 判断，都没有时检测响应体是否为 UTF-8，否则使用下载器设置的默认编码，
+// 没有设置默认编码时按双字节的分布识别 GBK、Big5，仍无法判断时使用 windows-1252
+func (r *Response) Encoding() string {
+	r.detect()
+	return r.encodingName
+}
+
+// detect 检测响应体的字符编码
+func (r *Response) detect() {
+	if r.encodingName != "" {
+		return
+	}
+	body := r.Body()
+	var contentType string
+	if r.Response != nil {
+		contentType = r.Header.Get("Content-Type")
+	}
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+	if !certain && name == "windows-1252" {
+		// 前1024字节中没有找到编码声明，也无法判断是否为 UTF-8
+		switch {
+		case utf8.Valid(body):
+			enc, name = encoding.Nop, "utf-8"
+		case r.defaultEncoding != "":
+			if e, n := charset.Lookup(r.defaultEncoding); e != nil {
+				enc, name = e, n
+			}
+		default:
+			if e, n := sniffCJK(body); e != nil {
+				enc, name = e, n
+			}
+		}
+	}
+	r.encoding, r.encodingName = enc, name
+}
+
+// sniffCJK 按双字节的分布识别没有声明编码的 GBK、Big5 响应体，无法判断时返回nil：
+// 1、GBK(GB2312)的汉字和标点集中在高字节 A1~F7、低字节 A1~FE 的区域
+// 2、Big5 的常用汉字和标点集中在高字节 A1~C6 的区域，低字节还包括 40~7E
+// 3、响应体必须能完整解码，避免把带重音字母的西文误判为中文
+func sniffCJK(body []byte) (encoding.Encoding, string) {
+	var pairs, gb, big5 int
+	for i := 0; i < len(body)-1; i++ {
+		lead := body[i]
+		if lead < 0x80 {
+			continue
+		}
+		trail := body[i+1]
+		i++
+		pairs++
+		if lead >= 0xA1 && lead <= 0xF7 && trail >= 0xA1 && trail <= 0xFE {
+			gb++
+		}
+		if lead >= 0xA1 && lead <= 0xC6 && (trail >= 0x40 && trail <= 0x7E || trail >= 0xA1 && trail <= 0xFE) {
+			big5++
+		}
+	}
+	var label string
+	switch {
+	case pairs == 0:
+		return nil, ""
+	case float64(gb) >= 0.9*float64(pairs):
+		label = "gbk"
+	case float64(big5) >= 0.8*float64(pairs):
+		label = "big5"
+	default:
+		return nil, ""
+	}
+	enc, name := charset.Lookup(label)
+	if decoded, err := enc.NewDecoder().Bytes(body); err != nil || bytes.ContainsRune(decoded, utf8.RuneError) {
+		return nil, ""
+	}
+	return enc, name
+}
+
+// Text 转码为 UTF-8 的响应体文本，只转码一次
+func (r *Response) Text() string {
+	if r.text != nil {
+		return *r.text
+	}
+	r.detect()
+	body := r.Body()
+	var text string
+	if r.encodingName == "utf-8" {
+		body = bytes.TrimPrefix(body, utf8BOM)
+		text = *(*string)(unsafe.Pointer(&body))
+	} else if decoded, err := r.encoding.NewDecoder().Bytes(body); err != nil {
+		log.Printf("%s decode %s failed: %v\n", r.URL(), r.encodingName, err)
+		text = string(body)
+	} else {
+		text = *(*string)(unsafe.Pointer(&decoded))
+	}
+	r.text = &text
+	return text
+}
+
+// SetDefaultEncoding 设置无法判断响应体编码时使用的默认编码，如 gbk，
+// 默认识别 GBK、Big5，仍无法判断时按 HTML 标准使用 windows-1252
+func (d *downloader) SetDefaultEncoding(name string) {
+	d.defaultEncoding = name
+}
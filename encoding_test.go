@@ -0,0 +1,74 @@
+package gugo
+
+import (
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"net/http"
+	"testing"
+)
+
+func newTextResponse(contentType string, body []byte, defaultEncoding string) *Response {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	res := &Response{Response: &http.Response{Header: header}, defaultEncoding: defaultEncoding}
+	res.setBody(body)
+	return res
+}
+
+func TestEncoding(t *testing.T) {
+	zh := "中华人民共和国成立于一九四九年，首都是北京。"
+	tw := "中華民國的首都是臺北，我們今天去公園散步。"
+	latin := "Ça coûte très cher, un café élégant à Zürich"
+	gbk, _ := simplifiedchinese.GBK.NewEncoder().String(zh)
+	big5, _ := traditionalchinese.Big5.NewEncoder().String(tw)
+	cp1252, _ := charmap.Windows1252.NewEncoder().String(latin)
+	tests := []struct {
+		name            string
+		contentType     string
+		body            string
+		defaultEncoding string
+		encoding        string
+		text            string
+	}{
+		{"utf-8 undeclared", "text/html", zh, "", "utf-8", zh},
+		{"utf-8 bom", "text/html", "\xEF\xBB\xBF" + zh, "", "utf-8", zh},
+		{"content-type", "text/html; charset=gbk", gbk, "", "gbk", zh},
+		{"meta charset", "text/html", `<meta charset="big5">` + big5, "", "big5", `<meta charset="big5">` + tw},
+		{"gbk undeclared", "text/html", gbk, "", "gbk", zh},
+		{"big5 undeclared", "text/html", big5, "", "big5", tw},
+		{"latin undeclared", "text/html", cp1252, "", "windows-1252", latin},
+		{"default encoding", "text/html", cp1252, "iso-8859-15", "iso-8859-15", latin},
+		{"declaration wins over default", "text/html; charset=gbk", gbk, "big5", "gbk", zh},
+	}
+	for _, tt := range tests {
+		res := newTextResponse(tt.contentType, []byte(tt.body), tt.defaultEncoding)
+		if got := res.Encoding(); got != tt.encoding {
+			t.Errorf("%s: Encoding = %q, want %q", tt.name, got, tt.encoding)
+		}
+		if got := res.Text(); got != tt.text {
+			t.Errorf("%s: Text = %q, want %q", tt.name, got, tt.text)
+		}
+	}
+}
+
+func TestSniffCJK(t *testing.T) {
+	short, _ := traditionalchinese.Big5.NewEncoder().String("臺北")
+	tests := []struct {
+		name string
+		body []byte
+		want string
+	}{
+		{"ascii", []byte("hello"), ""},
+		{"short big5", []byte(short), "big5"},
+		{"latin", []byte{'c', 'a', 'f', 0xE9, ' ', 0xE9, 'l', 0xE9, 'g', 'a', 'n', 't'}, ""},
+		{"truncated gbk", []byte{0xD6, 0xD0, 0xB9}, ""},
+	}
+	for _, tt := range tests {
+		if _, got := sniffCJK(tt.body); got != tt.want {
+			t.Errorf("%s: sniffCJK = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package gugo
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 )
 
@@ -14,9 +15,10 @@ const (
 var ctx, cancel = context.WithCancel(context.Background())
 
 type engine struct {
-	maxIdle   uint64        // 最大休眠次数
-	drag      chan uint64   // 阻尼器
-	heartbeat time.Duration // 心跳检测间隔时间
+	maxIdle    uint64        // 最大休眠次数
+	drag       chan uint64   // 阻尼器
+	heartbeat  time.Duration // 心跳检测间隔时间
+	checkpoint string        // 断点目录
 	*spider
 	*pipeline
 	*scheduler
@@ -24,7 +26,7 @@ type engine struct {
 }
 
 func newEngine() *engine {
-	return &engine{
+	e := &engine{
 		maxIdle:    MaxIdle,
 		heartbeat:  HeartBeat,
 		drag:       make(chan uint64, 1),
@@ -33,10 +35,13 @@ func newEngine() *engine {
 		scheduler:  newScheduler(),
 		downloader: newDownloader(),
 	}
+	e.scheduler.isCacheHit = e.downloader.isCacheHit
+	return e
 }
 
 // coordinate 引擎协调各组件工作
 func (e *engine) coordinate() {
+	e.watchCheckpoint()
 	e.monitorEngine()
 	e.monitorPipeline()
 	e.roundRobin()
@@ -44,17 +49,30 @@ func (e *engine) coordinate() {
 	e.summary()
 }
 
-// roundRobin 轮询调度
+// roundRobin 轮询调度：请求在获得全局并发许可后，按出队顺序从请求队列中取出
 func (e *engine) roundRobin() {
 	go func() {
 		for {
 			select {
-			case req := <-e.reqBuf:
-				e.concurrentRequest <- struct{}{}
-				go e.download(req, e.concurrentRequest, e.reqBuf, e.resBuf)
+			case e.concurrentRequest <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			req := e.next()
+			if req == nil {
+				<-e.concurrentRequest
+				return
+			}
+			go e.dispatch(req)
+		}
+	}()
+	go func() {
+		for {
+			select {
 			case res := <-e.resBuf:
+				res.engine = e
 				e.concurrentResponse <- struct{}{}
-				go e.response(res)
+				go e.parse(res)
 			case <-ctx.Done():
 				return
 			}
@@ -62,6 +80,63 @@ func (e *engine) roundRobin() {
 	}()
 }
 
+// next 等待并取出下一个可以立即下载的请求，引擎停止时返回nil
+func (e *engine) next() *Request {
+	for {
+		r, wait := e.frontier.pop(e.slot)
+		if r != nil {
+			return r
+		}
+		if wait <= 0 {
+			wait = e.heartbeat
+		}
+		select {
+		case <-e.frontier.ready:
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// dispatch 请求已占用域名下载槽和全局并发许可，交给下载器下载，命中HTTP缓存的请求没有占用下载槽
+func (e *engine) dispatch(req *Request) {
+	e.scheduler.IncrHandlingNumber()
+	defer e.scheduler.DecrHandlingNumber()
+	var sl *slot
+	if !req.cacheHit {
+		sl = e.slot(req.Host())
+		defer sl.release()
+	}
+	if err := e.resolve(req); err != nil {
+		log.Println(err)
+		<-e.concurrentRequest
+		e.downloader.IncrFailedCount()
+		e.untrack(req)
+		req.fail(err)
+		return
+	}
+	r, res := e.download(req, sl, e.concurrentRequest)
+	switch {
+	case r != nil:
+		e.retrack(req, r)
+		delay := r.retryDelay
+		r.retryDelay, r.cacheHit = 0, false
+		e.frontier.pushAfter(r, delay)
+	case res != nil:
+		e.retrack(req, res.Request)
+		go func() { e.resBuf <- res }()
+	default:
+		e.untrack(req)
+	}
+}
+
+// parse 爬虫解析响应，解析完成后请求才算处理完毕
+func (e *engine) parse(res *Response) {
+	e.response(res)
+	e.untrack(res.Request)
+}
+
 // monitor 引擎监控单元，监控项：scheduler、downloader、spider
 func (e *engine) monitorEngine() {
 	go func() {
@@ -85,22 +160,27 @@ func (e *engine) monitorEngine() {
 					}
 				}
 			}
-			time.Sleep(e.heartbeat)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(e.heartbeat):
+			}
 			count++
 		}
 	}()
 }
 
 // monitorPipeline 管道监控单元，监控项：pipeline
+// 引擎停止且爬虫没有正在解析的响应后，管道才可能被关闭，避免解析器向已关闭的管道发送数据
 func (e *engine) monitorPipeline() {
 	go func() {
 		var count uint64
 		for {
-			if e.Empty() {
+			if e.drained() {
 				count++
 			}
 			if count >= e.maxIdle {
-				if e.Empty() {
+				if e.drained() {
 					close(e.pipeBuf)
 					return
 				} else {
@@ -112,9 +192,35 @@ func (e *engine) monitorPipeline() {
 	}()
 }
 
+// emit 请求或数据经过爬虫中间件后分发，请求交给调度器，数据交给管道，
+// 响应产生的请求深度为响应深度加1
+func (e *engine) emit(res *Response, result interface{}) {
+	if r, ok := result.(*Request); ok && res != nil {
+		r.depth = res.depth + 1
+	}
+	if result = e.processSpiderOutput(res, result); result == nil {
+		return
+	}
+	if r, ok := result.(*Request); ok {
+		if r.callback == "" && r.parser != nil {
+			r.callback = e.callbackName(r.parser)
+		}
+		e.ask(r)
+		return
+	}
+	e.push(result)
+}
+
+// drained 引擎已停止、爬虫没有正在解析的响应、且数据管道为空(包括正在发送的数据)，
+// 解析器发送数据时先计数再结束解析，因此此时不会再有数据发送到管道
+func (e *engine) drained() bool {
+	return ctx.Err() != nil && e.spider.HandlingNumber() == 0 && e.Empty()
+}
+
 // idle 引擎休眠逻辑
 func (e *engine) idle() bool {
-	if e.scheduler.HandlingNumber() == 0 &&
+	if e.frontier.len() == 0 &&
+		e.scheduler.HandlingNumber() == 0 &&
 		e.downloader.HandlingNumber() == 0 &&
 		e.spider.HandlingNumber() == 0 {
 		return true
@@ -144,5 +250,8 @@ func (e *engine) summary() {
 	fmt.Printf("客户端请求被接受的数量: %d个\n", e.scheduler.AcceptedCount())
 	fmt.Printf("客户端请求下载失败数量: %d个\n", e.downloader.FailedCount())
 	fmt.Printf("客户端请求下载成功数量: %d个\n", e.downloader.CompletedCount())
+	fmt.Printf("客户端请求下载出错比例: %.2f%%\n", e.downloader.ErrorRate()*100)
+	fmt.Printf("客户端请求平均下载耗时: %s\n", e.downloader.AverageLatency())
+	fmt.Printf("域名熔断器打开的次数: %d次\n", e.downloader.TripCount())
 	fmt.Println("* * * * * * * * * * * * * * * * 统计信息 * * * * * * * * * * * * * * * *")
 }
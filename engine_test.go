@@ -0,0 +1,117 @@
+package gugo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestCrawl 完整运行一次爬虫：重试、熔断、最大深度。
+// 引擎使用包级别的 ctx，同一进程只能运行一次 GooGol，其他测试不要启动引擎
+func TestCrawl(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		n := hits[r.URL.Path]
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/flaky":
+			if n <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, "ok")
+		default:
+			// 每个页面链接到下一层：/ -> /d1 -> /d2 -> /d3 -> /d4
+			next := "d1"
+			if strings.HasPrefix(r.URL.Path, "/d") {
+				next = fmt.Sprintf("d%c", r.URL.Path[2]+1)
+			}
+			fmt.Fprintf(w, `<html><body><a href="%s">next</a></body></html>`, next)
+		}
+	}))
+	defer site.Close()
+	var downHits int
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		downHits++
+		mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer down.Close()
+
+	g := CreateGuGo()
+	g.SetHearBeat(20 * time.Millisecond)
+	g.SetMaxIdle(5)
+	g.SetMaxRetry(3)
+	g.SetRetryBackoff(10*time.Millisecond, 50*time.Millisecond)
+	g.SetRetryJitter(0)
+	g.SetDepthLimit(2)
+	g.SetCircuitBreaker(2, time.Minute)
+	g.SetCircuitBreakerFailFast(true)
+	g.SetDomainConcurrent(strings.TrimPrefix(down.URL, "http://"), 1)
+
+	depths := make(map[string]int)
+	var parse Parser
+	parse = func(res *Response) {
+		mu.Lock()
+		depths[res.Request.Request.URL.Path] = res.Depth()
+		mu.Unlock()
+		if err := res.FollowAll(res.CSS("a[href]"), parse, nil); err != nil {
+			t.Error(err)
+		}
+	}
+	var errs []error
+	errBack := ErrBack(func(r *Request, err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	})
+	g.Request(site.URL+"/", parse, nil)
+	g.Request(site.URL+"/flaky", parse, nil)
+	for i := 0; i < 4; i++ {
+		g.Request(fmt.Sprintf("%s/%d", down.URL, i), parse, nil, errBack)
+	}
+	g.GooGol()
+
+	mu.Lock()
+	defer mu.Unlock()
+	// 重试：前两次503，第三次成功
+	if hits["/flaky"] != 3 {
+		t.Errorf("/flaky hits = %d, want 3", hits["/flaky"])
+	}
+	if _, ok := depths["/flaky"]; !ok {
+		t.Error("/flaky is not parsed after retries")
+	}
+	// 最大深度：/d3 的深度为3，被调度器拦截
+	for path, want := range map[string]int{"/": 0, "/d1": 1, "/d2": 2} {
+		if got, ok := depths[path]; !ok || got != want {
+			t.Errorf("%s depth = %d, %v, want %d", path, got, ok, want)
+		}
+	}
+	if hits["/d3"] != 0 {
+		t.Errorf("/d3 hits = %d, want 0 beyond the depth limit", hits["/d3"])
+	}
+	// 熔断：连续失败2次后熔断器打开，其余请求直接失败
+	if downHits != 2 {
+		t.Errorf("down hits = %d, want 2 before the breaker opens", downHits)
+	}
+	if g.downloader.TripCount() != 1 {
+		t.Errorf("trip count = %d, want 1", g.downloader.TripCount())
+	}
+	if len(errs) != 4 {
+		t.Fatalf("errBack calls = %d, want 4", len(errs))
+	}
+	for _, err := range errs {
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("err = %v, want ErrCircuitOpen", err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package gugo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// 请求失败的错误类型，可以通过 errors.Is 判断
+var (
+	ErrNetwork       = errors.New("network error")              // 网络错误
+	ErrTimeout       = errors.New("timeout")                    // 连接或读写超时
+	ErrRetryHTTPCode = errors.New("retry http code exhausted")  // 重试状态码重试次数耗尽
+	ErrFiltered      = errors.New("request filtered")           // 被调度器或 robots.txt 过滤
+	ErrCallback      = errors.New("callback is not registered") // 解析器名字未注册
+	ErrCircuitOpen   = errors.New("circuit breaker is open")    // 域名熔断器打开
+	ErrNoProxy       = errors.New("no proxy available")         // 代理池中的代理都被禁用
+	ErrTooLarge      = errors.New("response too large")         // 响应体超过最大字节数
+)
+
+// RequestError 请求失败的错误
+type RequestError struct {
+	Kind       error         // 错误类型
+	URL        string        // 请求链接
+	StatusCode int           // 响应状态码，仅 ErrRetryHTTPCode 有效
+	RetryAfter time.Duration // 响应头 Retry-After 指定的等待时间，仅 ErrRetryHTTPCode 有效
+	Err        error         // 原始错误
+}
+
+func (e *RequestError) Error() string {
+	switch {
+	case e.StatusCode != 0:
+		return fmt.Sprintf("%s: %v: http code %d", e.URL, e.Kind, e.StatusCode)
+	case e.Err != nil:
+		return fmt.Sprintf("%s: %v: %v", e.URL, e.Kind, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.URL, e.Kind)
+}
+
+func (e *RequestError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// networkError 客户端请求错误分类为超时或网络错误
+func networkError(r *Request, err error) error {
+	kind := ErrNetwork
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() || errors.Is(err, context.DeadlineExceeded) {
+		kind = ErrTimeout
+	}
+	return &RequestError{Kind: kind, URL: r.URL(), Err: err}
+}
@@ -0,0 +1,74 @@
+package gugo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestErrBack(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/slow":
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	tests := []struct {
+		url    string
+		opts   []RequestOption
+		kind   error
+		status int
+	}{
+		{srv.URL + "/unavailable", nil, ErrRetryHTTPCode, http.StatusServiceUnavailable},
+		{closed.URL, nil, ErrNetwork, 0},
+		{srv.URL + "/slow", []RequestOption{Timeout(20 * time.Millisecond)}, ErrTimeout, 0},
+	}
+	d := newDownloader()
+	d.SetMaxRetry(1)
+	for _, tt := range tests {
+		var calls int
+		var failed error
+		errBack := ErrBack(func(_ *Request, err error) {
+			calls++
+			failed = err
+		})
+		r, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+		next, res := downloadOnce(d, NewRequest(r, nil, nil, append(tt.opts, errBack)...))
+		// 重试一次后最终失败
+		if next != nil {
+			next, res = downloadOnce(d, next)
+		}
+		if next != nil || res != nil || calls != 1 {
+			t.Errorf("%s: request = %v, response = %v, errBack calls = %d, want one failure", tt.url, next, res, calls)
+			continue
+		}
+		var re *RequestError
+		if !errors.As(failed, &re) || !errors.Is(failed, tt.kind) || re.URL != tt.url || re.StatusCode != tt.status {
+			t.Errorf("%s: err = %#v, want %v with status %d", tt.url, failed, tt.kind, tt.status)
+		}
+	}
+	if d.FailedCount() != uint64(len(tests)) {
+		t.Errorf("failed count = %d, want %d", d.FailedCount(), len(tests))
+	}
+}
+
+func TestErrBackFiltered(t *testing.T) {
+	s := newScheduler()
+	var failed []error
+	for i := 0; i < 2; i++ {
+		r, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+		s.ask(NewRequest(r, func(*Response) {}, nil, ErrBack(func(_ *Request, err error) {
+			failed = append(failed, err)
+		})))
+	}
+	if len(failed) != 1 || !errors.Is(failed[0], ErrFiltered) {
+		t.Errorf("errBack errs = %v, want ErrFiltered for the duplicate", failed)
+	}
+}
@@ -30,14 +30,16 @@ func (ms *MySpider) Parse1(response *gugo.Response) {
 	_ = response.Method()
 
 	// 模拟发送从页面提取的数据
-	ms.Push(Film{score: 5, title: "肖申克的救赎"})
+	// 在解析器中通过响应发送数据和请求，爬虫中间件的 ProcessSpiderOutput 可以拿到当前响应(如来源链接)
+	response.Push(Film{score: 5, title: "肖申克的救赎"})
 
 	// 模拟发送从页面提取的新链接
-	// Request方法是简易版的GET请求，客户端只需传入URL，自定义解析函数，元数据即可
+	// Get方法是简易版的GET请求，客户端只需传入URL，自定义解析函数，元数据即可
+	// 在解析器中通过响应发起请求，新请求的深度为响应深度加1，最大深度(SetDepthLimit)才能生效
 	// 新链接：必填
 	// 解析器：必填
 	// 元数据：可选
-	ms.Request("https://www.douban.com", ms.Parse2, map[string]interface{}{"parser": "Parser1"})
+	response.Get("https://www.douban.com", ms.Parse2, map[string]interface{}{"parser": "Parser1"})
 }
 
 func (ms *MySpider) Parse2(response *gugo.Response) {
@@ -45,7 +47,7 @@ func (ms *MySpider) Parse2(response *gugo.Response) {
 	_ = response.Meta()
 
 	// 模拟发送从页面提取的数据
-	ms.Push(Film{score: 4, title: "越狱"})
+	response.Push(Film{score: 4, title: "越狱"})
 
 	// 模拟发送从页面提取的新链接
 	// NativeRequest方法是原生版的http请求，客户端需要传入自定义*http.Request、自定义解析函数、元数据
@@ -53,10 +55,13 @@ func (ms *MySpider) Parse2(response *gugo.Response) {
 	// 解析器：必填
 	// 元数据：可选
 	customGetRequest, _ := http.NewRequest(http.MethodGet, "https://www.tencent.com", nil)
-	ms.NativeRequest(customGetRequest, ms.Parser3, map[string]interface{}{"parser": "Parser2"})
+	response.NativeRequest(customGetRequest, ms.Parser3, map[string]interface{}{"parser": "Parser2"})
 
 	customPostRequest, _ := http.NewRequest(http.MethodPost, "https://www.abc.com", nil)
-	ms.NativeRequest(customPostRequest, ms.Parser3, map[string]interface{}{"parser": "Parser2"})
+	response.NativeRequest(customPostRequest, ms.Parser3, map[string]interface{}{"parser": "Parser2"})
+
+	// Follow方法跟随页面中的链接，相对链接会被转换为绝对链接
+	response.FollowAll(response.CSS("a[href]"), ms.Parser3, nil)
 }
 
 func (ms *MySpider) Parser3(response *gugo.Response) {
@@ -64,7 +69,7 @@ func (ms *MySpider) Parser3(response *gugo.Response) {
 	_ = response.Meta()
 
 	// 模拟发送从页面提取的数据
-	ms.Push(Film{score: 3, title: "复仇者联盟"})
+	response.Push(Film{score: 3, title: "复仇者联盟"})
 }
 
 // ProcessItem 客户端自定义数据处理器
@@ -81,7 +86,7 @@ func main() {
 		GuGo:  gugo.CreateGuGo(),
 		block: make(chan struct{}),
 	}
-	// 2、发送初始请求
+	// 2、发送初始请求(深度为0)
 	ms.Request("https://www.baidu.com", ms.Parse1, nil)
 	// 3、异步处理客户端数据(保存到文件、数据库等操作)
 	go ms.ProcessItem()
@@ -0,0 +1,127 @@
+package gugo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// base 解析相对链接的基准URL：HTML文档中的 <base href>，其次是响应的最终URL(跟随重定向后)
+func (r *Response) base() *url.URL {
+	if r.baseURL != nil {
+		return r.baseURL
+	}
+	base := r.Request.Request.URL
+	if r.Response != nil && r.Response.Request != nil && r.Response.Request.URL != nil {
+		base = r.Response.Request.URL
+	}
+	if href := strings.TrimSpace(r.CSS("base[href]").Attr("href")); href != "" {
+		if u, err := base.Parse(href); err == nil {
+			base = u
+		}
+	}
+	r.baseURL = base
+	return base
+}
+
+// URLJoin 相对链接转换为绝对链接
+func (r *Response) URLJoin(href string) (string, error) {
+	u, err := r.base().Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// CarryMeta 复制当前请求元数据中的指定字段，用于传给 Follow 等创建的新请求
+func (r *Response) CarryMeta(keys ...string) map[string]interface{} {
+	meta := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		if v, ok := r.Request.meta[k]; ok {
+			meta[k] = v
+		}
+	}
+	return meta
+}
+
+// href 链接目标：字符串，或选择器(选中的字符串，或元素的 href、src 属性)
+func href(target interface{}) (string, error) {
+	switch t := target.(type) {
+	case string:
+		return t, nil
+	case *Selector:
+		if t.node == nil {
+			return t.text, nil
+		}
+		if v := t.Attr("href"); v != "" {
+			return v, nil
+		}
+		if v := t.Attr("src"); v != "" {
+			return v, nil
+		}
+		return "", fmt.Errorf("selector <%s> has no href or src attribute", t.node.Data)
+	}
+	return "", fmt.Errorf("unsupported follow target type %T", target)
+}
+
+// Follow 跟随链接发起新请求：target 为链接字符串或选择器，相对链接按 URLJoin 转换，
+// 去掉链接中的 #fragment，只跟随 http、https 链接。新请求沿用当前请求的会话，
+// 元数据不会自动复制，需要时通过 CarryMeta 选择字段；请求经过 Response.NativeRequest 发出
+func (r *Response) Follow(target interface{}, parser Parser, meta map[string]interface{}, opts ...RequestOption) error {
+	h, err := href(target)
+	if err != nil {
+		return err
+	}
+	u, err := r.base().Parse(strings.TrimSpace(h))
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported follow url: %s", u)
+	}
+	u.Fragment, u.RawFragment = "", ""
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	opts = append([]RequestOption{Session(r.Request.session)}, opts...)
+	r.NativeRequest(req, parser, meta, opts...)
+	return nil
+}
+
+// FollowAll 跟随多个链接，targets 为 []string 或 SelectorList，无法跟随的链接会被跳过并返回合并的错误
+func (r *Response) FollowAll(targets interface{}, parser Parser, meta map[string]interface{}, opts ...RequestOption) error {
+	var list []interface{}
+	switch t := targets.(type) {
+	case []string:
+		for _, s := range t {
+			list = append(list, s)
+		}
+	case SelectorList:
+		for _, s := range t {
+			list = append(list, s)
+		}
+	default:
+		return fmt.Errorf("unsupported follow targets type %T", targets)
+	}
+	var errs []string
+	for _, target := range list {
+		m := meta
+		if meta != nil {
+			// 每个请求使用独立的元数据，避免相互影响
+			m = make(map[string]interface{}, len(meta))
+			for k, v := range meta {
+				m[k] = v
+			}
+		}
+		if err := r.Follow(target, parser, m, opts...); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
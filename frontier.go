@@ -0,0 +1,203 @@
+package gugo
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Order 请求的出队顺序
+type Order uint8
+
+const (
+	OrderPriority Order = iota // 优先级高的请求先下载，优先级相同时先进先出
+	OrderBFS                   // 广度优先：先进先出，忽略优先级
+	OrderDFS                   // 深度优先：后进先出，忽略优先级
+)
+
+// frontier 待下载的请求队列，每个域名一个堆，出队时只考虑下载槽可以立即占用的域名，
+// 被限速的域名不会占用全局并发许可，也不会阻塞其他域名的请求
+type frontier struct {
+	mu            sync.Mutex
+	order         Order                 // 出队顺序
+	depthPriority int                   // 每层深度调整的优先级
+	seq           uint64                // 入队序号
+	size          int                   // 请求数量
+	queues        map[string]*hostQueue // 域名对应的请求堆
+	cacheHits     *hostQueue            // 命中HTTP缓存的请求，出队时不占用域名下载槽
+	delayed       delayQueue            // 等待重试的请求，到期后才进入域名对应的请求堆
+	ready         chan struct{}         // 有新请求入队或下载槽可能变为可用的信号
+}
+
+func newFrontier() *frontier {
+	f := &frontier{
+		queues: make(map[string]*hostQueue),
+		ready:  make(chan struct{}, 1),
+	}
+	f.cacheHits = &hostQueue{f: f}
+	return f
+}
+
+type frontierItem struct {
+	r   *Request
+	seq uint64
+	at  time.Time // 到期时间，仅等待重试的请求有效
+}
+
+// hostQueue 单个域名的请求堆
+type hostQueue struct {
+	f     *frontier
+	items []*frontierItem
+}
+
+func (q *hostQueue) Len() int           { return len(q.items) }
+func (q *hostQueue) Less(i, j int) bool { return q.f.less(q.items[i], q.items[j]) }
+func (q *hostQueue) Swap(i, j int)      { q.items[i], q.items[j] = q.items[j], q.items[i] }
+func (q *hostQueue) Push(x interface{}) { q.items = append(q.items, x.(*frontierItem)) }
+func (q *hostQueue) Pop() interface{} {
+	n := len(q.items) - 1
+	item := q.items[n]
+	q.items[n] = nil
+	q.items = q.items[:n]
+	return item
+}
+
+// delayQueue 按到期时间排序的请求堆
+type delayQueue []*frontierItem
+
+func (q delayQueue) Len() int            { return len(q) }
+func (q delayQueue) Less(i, j int) bool  { return q[i].at.Before(q[j].at) }
+func (q delayQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *delayQueue) Push(x interface{}) { *q = append(*q, x.(*frontierItem)) }
+func (q *delayQueue) Pop() interface{} {
+	old := *q
+	n := len(old) - 1
+	item := old[n]
+	old[n] = nil
+	*q = old[:n]
+	return item
+}
+
+// less 请求a是否比请求b先出队
+func (f *frontier) less(a, b *frontierItem) bool {
+	switch f.order {
+	case OrderBFS:
+		return a.seq < b.seq
+	case OrderDFS:
+		return a.seq > b.seq
+	}
+	if pa, pb := f.priority(a.r), f.priority(b.r); pa != pb {
+		return pa > pb
+	}
+	return a.seq < b.seq
+}
+
+// priority 请求出队时的优先级：请求的优先级按深度调整
+func (f *frontier) priority(r *Request) int {
+	return r.priority - r.depth*f.depthPriority
+}
+
+// push 请求入队
+func (f *frontier) push(r *Request) {
+	f.mu.Lock()
+	f.enqueue(&frontierItem{r: r})
+	f.size++
+	f.mu.Unlock()
+	f.signal()
+}
+
+// pushAfter 请求等待一段时间后才入队，等待期间不占用任何下载槽和并发许可
+func (f *frontier) pushAfter(r *Request, delay time.Duration) {
+	if delay <= 0 {
+		f.push(r)
+		return
+	}
+	f.mu.Lock()
+	heap.Push(&f.delayed, &frontierItem{r: r, at: time.Now().Add(delay)})
+	f.size++
+	f.mu.Unlock()
+	f.signal()
+}
+
+// enqueue 请求放入域名对应的请求堆，命中HTTP缓存的请求单独放入一个堆
+func (f *frontier) enqueue(item *frontierItem) {
+	f.seq++
+	item.seq = f.seq
+	if item.r.cacheHit {
+		heap.Push(f.cacheHits, item)
+		return
+	}
+	q, ok := f.queues[item.r.Host()]
+	if !ok {
+		q = &hostQueue{f: f}
+		f.queues[item.r.Host()] = q
+	}
+	heap.Push(q, item)
+}
+
+// promote 到期的重试请求放入域名对应的请求堆，返回下一个重试请求到期需要等待的时间
+func (f *frontier) promote() time.Duration {
+	now := time.Now()
+	for len(f.delayed) > 0 {
+		if wait := f.delayed[0].at.Sub(now); wait > 0 {
+			return wait
+		}
+		f.enqueue(heap.Pop(&f.delayed).(*frontierItem))
+	}
+	return 0
+}
+
+// pop 命中HTTP缓存的请求不占用下载槽，最先出队；否则从下载槽可以立即占用的域名中取出最先出队的请求，
+// 并占用该域名的下载槽；没有这样的请求时返回nil和需要等待的时间，等待时间为0表示只能等待信号
+func (f *frontier) pop(slotOf func(host string) *slot) (*Request, time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for {
+		var best *hostQueue
+		var bestHost string
+		wait := f.promote()
+		if f.cacheHits.Len() > 0 {
+			f.size--
+			return heap.Pop(f.cacheHits).(*frontierItem).r, 0
+		}
+		for host, q := range f.queues {
+			w := slotOf(host).ready()
+			if w != 0 {
+				if w > 0 && (wait == 0 || w < wait) {
+					wait = w
+				}
+				continue
+			}
+			if best == nil || f.less(q.items[0], best.items[0]) {
+				best, bestHost = q, host
+			}
+		}
+		if best == nil {
+			return nil, wait
+		}
+		if !slotOf(bestHost).tryAcquire() {
+			continue
+		}
+		item := heap.Pop(best).(*frontierItem)
+		if best.Len() == 0 {
+			delete(f.queues, bestHost)
+		}
+		f.size--
+		return item.r, 0
+	}
+}
+
+// signal 通知等待出队的一方
+func (f *frontier) signal() {
+	select {
+	case f.ready <- struct{}{}:
+	default:
+	}
+}
+
+// len 队列中的请求数量
+func (f *frontier) len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.size
+}
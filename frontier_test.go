@@ -0,0 +1,114 @@
+package gugo
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFrontierOrder(t *testing.T) {
+	tests := []struct {
+		name          string
+		order         Order
+		depthPriority int
+		want          []string
+	}{
+		{"priority", OrderPriority, 0, []string{"b", "d", "a", "c"}},
+		{"priority by depth", OrderPriority, 3, []string{"b", "a", "d", "c"}},
+		{"bfs", OrderBFS, 0, []string{"a", "b", "c", "d"}},
+		{"dfs", OrderDFS, 0, []string{"d", "c", "b", "a"}},
+	}
+	for _, tt := range tests {
+		f := newFrontier()
+		f.order, f.depthPriority = tt.order, tt.depthPriority
+		requests := []struct {
+			path     string
+			priority int
+			depth    int
+		}{
+			{"a", 0, 0},
+			{"b", 5, 0},
+			{"c", 0, 1},
+			{"d", 5, 2},
+		}
+		for _, r := range requests {
+			req, _ := http.NewRequest(http.MethodGet, "http://example.com/"+r.path, nil)
+			f.push(&Request{Request: req, priority: r.priority, depth: r.depth})
+		}
+		slots := make(map[string]*slot)
+		slotOf := func(host string) *slot {
+			if _, ok := slots[host]; !ok {
+				slots[host] = newSlot(host, 0, 1, 0, nil)
+			}
+			return slots[host]
+		}
+		var got []string
+		for f.len() > 0 {
+			r, _ := f.pop(slotOf)
+			if r == nil {
+				t.Fatalf("%s: pop returned nil with %d requests queued", tt.name, f.len())
+			}
+			got = append(got, r.Request.URL.Path[1:])
+			for _, want := range requests {
+				if want.path == got[len(got)-1] && r.Priority() != want.priority {
+					t.Errorf("%s: %s priority changed to %d", tt.name, want.path, r.Priority())
+				}
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: order = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFrontierSkipsBlockedHost(t *testing.T) {
+	f := newFrontier()
+	for _, u := range []string{"http://a.com/1", "http://b.com/1"} {
+		req, _ := http.NewRequest(http.MethodGet, u, nil)
+		f.push(&Request{Request: req, priority: map[string]int{"a.com": 1}[req.Host]})
+	}
+	a, b := newSlot("a.com", 0, 1, 0, nil), newSlot("b.com", 0, 1, 0, nil)
+	a.gate()
+	slotOf := func(host string) *slot {
+		if host == "a.com" {
+			return a
+		}
+		return b
+	}
+	if r, _ := f.pop(slotOf); r == nil || r.Host() != "b.com" {
+		t.Fatalf("pop = %v, want the request of b.com while a.com is gated", r)
+	}
+	if r, _ := f.pop(slotOf); r != nil {
+		t.Fatalf("pop = %s, want nil while a.com is gated", r.URL())
+	}
+	a.ungate()
+	if r, _ := f.pop(slotOf); r == nil || r.Host() != "a.com" {
+		t.Fatalf("pop = %v, want the request of a.com after ungate", r)
+	}
+}
+
+func TestFrontierCacheHit(t *testing.T) {
+	f := newFrontier()
+	sl := newSlot("a.com", time.Hour, 1, 1, nil)
+	slotOf := func(string) *slot { return sl }
+	for _, u := range []string{"http://a.com/1", "http://a.com/2", "http://a.com/3"} {
+		req, _ := http.NewRequest(http.MethodGet, u, nil)
+		f.push(&Request{Request: req, cacheHit: u != "http://a.com/1"})
+	}
+	var got []string
+	for {
+		r, _ := f.pop(slotOf)
+		if r == nil {
+			break
+		}
+		got = append(got, r.Request.URL.Path)
+	}
+	// 命中缓存的请求不占用下载槽，/1 占用唯一的令牌后其他请求仍然可以出队
+	if want := []string{"/2", "/3", "/1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pop order = %v, want %v", got, want)
+	}
+	if f.len() != 0 {
+		t.Errorf("len = %d, want 0", f.len())
+	}
+}
@@ -1,10 +1,20 @@
 module github.com/xiaogogonuo/gugo
 
-go 1.16
+go 1.18
 
 require (
 	github.com/PuerkitoBio/goquery v1.8.0
-	github.com/bits-and-blooms/bitset v1.2.2 // indirect
+	github.com/andybalholm/brotli v1.1.1
+	github.com/antchfx/htmlquery v1.3.4
+	github.com/antchfx/xpath v1.3.3
 	github.com/bits-and-blooms/bloom/v3 v3.2.0
-	golang.org/x/net v0.0.0-20220403103023-749bd193bc2b // indirect
+	github.com/klauspost/compress v1.16.7
+	golang.org/x/net v0.33.0
+	golang.org/x/text v0.21.0
+)
+
+require (
+	github.com/andybalholm/cascadia v1.3.1 // indirect
+	github.com/bits-and-blooms/bitset v1.2.2 // indirect
+	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 )
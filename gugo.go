@@ -1,10 +1,13 @@
 package gugo
 
 import (
+	"log"
 	"net/http"
+	"sync"
 )
 
 type GuGo struct {
+	depthWarning sync.Once // 解析器中通过 GuGo 发起请求时只提醒一次
 	*engine
 }
 
@@ -13,20 +16,29 @@ func CreateGuGo() *GuGo {
 	return &GuGo{engine: newEngine()}
 }
 
-// Request 简易版GET请求
-func (g *GuGo) Request(url string, parser Parser, meta map[string]interface{}) {
+// Request 简易版GET请求，请求深度为0，通常用于发起初始请求。
+// 解析器中请使用 Response.Get、Response.NativeRequest 或 Response.Follow，新请求的深度为响应深度加1，
+// 否则 Request.Depth 始终为0，SetDepthLimit、SetDepthPriority 不会生效
+func (g *GuGo) Request(url string, parser Parser, meta map[string]interface{}, opts ...RequestOption) {
 	request, _ := http.NewRequest(http.MethodGet, url, nil)
-	g.NativeRequest(request, parser, meta)
+	g.NativeRequest(request, parser, meta, opts...)
 }
 
-// NativeRequest 原生请求，客户端自定义
-func (g *GuGo) NativeRequest(r *http.Request, parser Parser, meta map[string]interface{}) {
-	g.ask(&request{r, parser, meta})
+// NativeRequest 原生请求，客户端自定义，请求深度为0，爬虫中间件收到的响应为nil，
+// 解析器中请使用 Response.NativeRequest；设置了最大深度或深度优先级时，解析期间通过这里发起请求会记录一次警告
+func (g *GuGo) NativeRequest(r *http.Request, parser Parser, meta map[string]interface{}, opts ...RequestOption) {
+	if (g.depthLimit > 0 || g.frontier.depthPriority != 0) && g.spider.HandlingNumber() > 0 {
+		g.depthWarning.Do(func() {
+			log.Println("GuGo.Request is called while parsing responses, its depth is 0 and the depth limit does not apply, " +
+				"use Response.Get, Response.NativeRequest or Response.Follow in parsers")
+		})
+	}
+	g.emit(nil, NewRequest(r, parser, meta, opts...))
 }
 
-// Push 客户端发送数据
+// Push 客户端发送数据，爬虫中间件收到的响应为nil，解析器中请使用 Response.Push
 func (g *GuGo) Push(item interface{}) {
-	g.push(item)
+	g.emit(nil, item)
 }
 
 // Pull 客户端下载数据
@@ -0,0 +1,39 @@
+package gugo
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDepthWarning(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	tests := []struct {
+		name       string
+		depthLimit uint32
+		parsing    bool
+		warnings   int
+	}{
+		{"initial requests", 2, false, 0},
+		{"no depth limit", 0, true, 0},
+		{"inside a parser", 2, true, 1},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		g := CreateGuGo()
+		g.SetDepthLimit(tt.depthLimit)
+		if tt.parsing {
+			g.spider.IncrHandlingNumber()
+		}
+		for i := 0; i < 3; i++ {
+			g.Request("http://example.com/", func(*Response) {}, nil)
+		}
+		if got := strings.Count(buf.String(), "depth limit does not apply"); got != tt.warnings {
+			t.Errorf("%s: warnings = %d, want %d", tt.name, got, tt.warnings)
+		}
+	}
+}
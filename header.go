@@ -0,0 +1,132 @@
+package gugo
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+)
+
+var (
+	// DefaultHeader 默认请求头，请求和请求头模板中没有的字段使用默认值，
+	// 默认的 User-Agent 为常见的桌面浏览器，替代 Go 客户端的 Go-http-client/1.1
+	DefaultHeader = http.Header{
+		"User-Agent":      {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"},
+		"Accept":          {"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+		"Accept-Language": {"en"},
+	}
+)
+
+// ProfileMode 请求头模板的使用方式
+type ProfileMode uint8
+
+const (
+	ProfileRotate  ProfileMode = iota // 每个请求轮换一个模板
+	ProfilePinHost                    // 同一域名固定使用第一次分配的模板
+)
+
+// headerProfiles 请求头模板池，模板通常包含 User-Agent、Accept-Language 等字段
+type headerProfiles struct {
+	mu       sync.Mutex
+	profiles []http.Header
+	mode     ProfileMode
+	next     int            // 下一个分配的模板
+	pinned   map[string]int // 域名固定使用的模板
+}
+
+// pick 为域名的请求选出一个模板
+func (hp *headerProfiles) pick(host string) http.Header {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+	if hp.mode == ProfilePinHost {
+		if i, ok := hp.pinned[host]; ok {
+			return hp.profiles[i]
+		}
+	}
+	i := hp.next
+	hp.next = (hp.next + 1) % len(hp.profiles)
+	if hp.mode == ProfilePinHost {
+		hp.pinned[host] = i
+	}
+	return hp.profiles[i]
+}
+
+// prepare 复制请求并补全请求头，优先级：请求自身的请求头 > 请求头模板 > 默认请求头，
+// 复制后的请求交给客户端下载，原请求不受影响，重试时会重新选择模板。
+// Clone 与原请求共享请求体，因此复制后的请求使用独立的请求体，原请求的请求体保留给重试、指纹和断点。
+// 直接构造的 http.Request 可能没有请求头，Clone 后仍为nil，需要先创建
+func (d *downloader) prepare(req *Request) *http.Request {
+	r := req.Request.Clone(req.Request.Context())
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+	if req.Request.Body != nil && req.Request.Body != http.NoBody {
+		body := req.Body()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		r.ContentLength = int64(len(body))
+	}
+	if d.headerProfiles != nil {
+		fillHeader(r.Header, d.headerProfiles.pick(req.Host()))
+	}
+	fillHeader(r.Header, d.defaultHeader)
+	if d.decompression && r.Header.Get("Accept-Encoding") == "" {
+		r.Header.Set("Accept-Encoding", AcceptEncoding)
+	}
+	return r
+}
+
+// fillHeader 把src中dst没有的字段补充到dst
+func fillHeader(dst, src http.Header) {
+	for k, v := range src {
+		if _, ok := dst[k]; !ok {
+			dst[k] = append([]string(nil), v...)
+		}
+	}
+}
+
+// canonicalHeader 复制请求头并规范字段名
+func canonicalHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+	for k, v := range h {
+		c[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+	}
+	return c
+}
+
+// SetDefaultHeader 设置默认请求头的字段，value为空表示删除该字段
+func (d *downloader) SetDefaultHeader(key, value string) {
+	d.defaultHeader = d.defaultHeader.Clone()
+	if value == "" {
+		d.defaultHeader.Del(key)
+		return
+	}
+	d.defaultHeader.Set(key, value)
+}
+
+// SetHeaderProfiles 设置请求头模板池，模板按 mode 分配给请求
+func (d *downloader) SetHeaderProfiles(mode ProfileMode, profiles ...http.Header) {
+	if len(profiles) == 0 {
+		d.headerProfiles = nil
+		return
+	}
+	hp := &headerProfiles{mode: mode, pinned: make(map[string]int)}
+	for _, p := range profiles {
+		hp.profiles = append(hp.profiles, canonicalHeader(p))
+	}
+	d.headerProfiles = hp
+}
+
+// Header 请求的请求头字段，优先于请求头模板和默认请求头
+func Header(key, value string) RequestOption {
+	return func(r *Request) {
+		if r.Request != nil {
+			if r.Request.Header == nil {
+				r.Request.Header = http.Header{}
+			}
+			r.Request.Header.Set(key, value)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package gugo
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestPrepareHeader(t *testing.T) {
+	d := newDownloader()
+	d.SetHeaderProfiles(ProfileRotate,
+		http.Header{"user-agent": {"profile-a"}, "Accept-Language": {"zh"}},
+		http.Header{"user-agent": {"profile-b"}},
+	)
+	r, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	req := NewRequest(r, nil, nil, Header("Accept", "application/json"))
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"Accept", "application/json"},
+		{"User-Agent", "profile-a"},
+		{"Accept-Language", "zh"},
+		{"Accept-Encoding", AcceptEncoding},
+	}
+	h := d.prepare(req).Header
+	for _, tt := range tests {
+		if got := h.Get(tt.key); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+	// 轮换模板，模板没有的字段使用默认值，原请求不受影响
+	h = d.prepare(req).Header
+	if got := h.Get("User-Agent"); got != "profile-b" {
+		t.Errorf("second User-Agent = %q, want profile-b", got)
+	}
+	if got := h.Get("Accept-Language"); got != DefaultHeader.Get("Accept-Language") {
+		t.Errorf("second Accept-Language = %q, want the default", got)
+	}
+	if len(req.Request.Header) != 1 {
+		t.Errorf("original header = %v, want only Accept", req.Request.Header)
+	}
+}
+
+func TestPrepareHeaderPinHost(t *testing.T) {
+	d := newDownloader()
+	d.SetHeaderProfiles(ProfilePinHost, http.Header{"User-Agent": {"a"}}, http.Header{"User-Agent": {"b"}})
+	want := map[string]string{"x.com": "a", "y.com": "b"}
+	for i := 0; i < 3; i++ {
+		for _, host := range []string{"x.com", "y.com"} {
+			r, _ := http.NewRequest(http.MethodGet, "http://"+host+"/", nil)
+			if got := d.prepare(NewRequest(r, nil, nil)).Header.Get("User-Agent"); got != want[host] {
+				t.Errorf("%s User-Agent = %q, want %q", host, got, want[host])
+			}
+		}
+	}
+}
+
+func TestPrepareNilHeader(t *testing.T) {
+	d := newDownloader()
+	u, _ := url.Parse("http://example.com/")
+	req := NewRequest(&http.Request{Method: http.MethodGet, URL: u}, nil, nil)
+	if got := d.prepare(req).Header.Get("User-Agent"); got != DefaultHeader.Get("User-Agent") {
+		t.Errorf("User-Agent = %q, want the default", got)
+	}
+	req = NewRequest(&http.Request{Method: http.MethodGet, URL: u}, nil, nil, Header("Referer", "http://example.com/"))
+	if got := d.prepare(req).Header.Get("Referer"); got != "http://example.com/" {
+		t.Errorf("Referer = %q, want the request header", got)
+	}
+}
@@ -0,0 +1,299 @@
+package gugo
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CachePolicy HTTP缓存策略
+type CachePolicy uint8
+
+const (
+	CacheAlways  CachePolicy = iota // 开发模式：有缓存就使用缓存，不管响应头
+	CacheRFC7234                    // 按 RFC 7234 判断缓存是否新鲜，过期后用 ETag/Last-Modified 发起条件请求
+)
+
+// httpCache 磁盘HTTP缓存，以请求指纹为键，每个响应保存为元数据和响应体两个文件
+type httpCache struct {
+	dir        string        // 缓存目录
+	policy     CachePolicy   // 缓存策略
+	expiration time.Duration // 缓存有效期，0表示永不过期
+	ignoreCode []int         // 不缓存的状态码
+}
+
+// cacheEntry 缓存的响应
+type cacheEntry struct {
+	URL        string      `json:"url"`
+	Method     string      `json:"method"`
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header"`
+	Time       time.Time   `json:"time"` // 保存或最近一次验证的时间
+	body       []byte
+}
+
+// key 请求指纹对应的缓存文件路径，不含扩展名，需要在下载之前计算
+func (c *httpCache) key(req *Request) string {
+	key := hex.EncodeToString(req.FingerPrint())
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// load 读取缓存，不存在或已超过有效期时返回nil
+func (c *httpCache) load(path string) *cacheEntry {
+	entry := c.loadMeta(path)
+	if entry == nil {
+		return nil
+	}
+	var err error
+	if entry.body, err = os.ReadFile(path + ".body"); err != nil {
+		return nil
+	}
+	return entry
+}
+
+// loadMeta 只读取缓存的元数据，不读取响应体
+func (c *httpCache) loadMeta(path string) *cacheEntry {
+	meta, err := os.ReadFile(path + ".json")
+	if err != nil {
+		return nil
+	}
+	entry := &cacheEntry{}
+	if err = json.Unmarshal(meta, entry); err != nil {
+		return nil
+	}
+	if c.expiration > 0 && time.Since(entry.Time) > c.expiration {
+		return nil
+	}
+	return entry
+}
+
+// store 保存缓存，先写临时文件再重命名
+func (c *httpCache) store(path string, entry *cacheEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err = writeCacheFile(path+".body", entry.body); err != nil {
+		return err
+	}
+	return writeCacheFile(path+".json", meta)
+}
+
+func writeCacheFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// isIgnoreCode 是否是不缓存的状态码
+func (c *httpCache) isIgnoreCode(code int) bool {
+	for _, ignore := range c.ignoreCode {
+		if ignore == code {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheable 响应是否可以缓存
+func (c *httpCache) cacheable(req *Request, res *http.Response) bool {
+	if c.isIgnoreCode(res.StatusCode) {
+		return false
+	}
+	if c.policy == CacheAlways {
+		return true
+	}
+	if req.Method() != http.MethodGet && req.Method() != http.MethodHead {
+		return false
+	}
+	_, reqNoStore := cacheControl(req.Request.Header)["no-store"]
+	_, resNoStore := cacheControl(res.Header)["no-store"]
+	return !reqNoStore && !resNoStore
+}
+
+// fresh 缓存是否新鲜，新鲜的缓存无需请求服务器
+func (c *httpCache) fresh(req *Request, entry *cacheEntry) bool {
+	if c.policy == CacheAlways {
+		return true
+	}
+	if _, ok := cacheControl(req.Request.Header)["no-cache"]; ok {
+		return false
+	}
+	cc := cacheControl(entry.Header)
+	if _, ok := cc["no-cache"]; ok {
+		return false
+	}
+	age := time.Since(entry.Time)
+	if seconds, err := strconv.Atoi(entry.Header.Get("Age")); err == nil && seconds > 0 {
+		age += time.Duration(seconds) * time.Second
+	}
+	return age < freshnessLifetime(cc, entry.Header)
+}
+
+// freshnessLifetime 响应的新鲜期：max-age，其次是 Expires-Date，最后按 Last-Modified 启发式估算(10%)
+func freshnessLifetime(cc map[string]string, header http.Header) time.Duration {
+	if v, ok := cc["max-age"]; ok {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return 0
+	}
+	if v := header.Get("Expires"); v != "" {
+		if expires, err := http.ParseTime(v); err == nil {
+			return expires.Sub(date)
+		}
+		return 0
+	}
+	if modified, err := http.ParseTime(header.Get("Last-Modified")); err == nil && date.After(modified) {
+		return date.Sub(modified) / 10
+	}
+	return 0
+}
+
+// cacheControl 解析 Cache-Control 头
+func cacheControl(header http.Header) map[string]string {
+	cc := make(map[string]string)
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "" {
+				continue
+			}
+			kv := strings.SplitN(directive, "=", 2)
+			if len(kv) == 1 {
+				kv = append(kv, "")
+			}
+			cc[strings.ToLower(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return cc
+}
+
+// response 缓存转换为响应
+func (entry *cacheEntry) response(req *Request) *Response {
+	res := &Response{
+		Response: &http.Response{
+			Status:        strconv.Itoa(entry.StatusCode) + " " + http.StatusText(entry.StatusCode),
+			StatusCode:    entry.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        entry.Header.Clone(),
+			ContentLength: int64(len(entry.body)),
+			Request:       req.Request,
+		},
+		Request: req,
+		cached:  true,
+	}
+	res.setBody(entry.body)
+	return res
+}
+
+// isCacheHit 请求是否有新鲜的缓存，请求被接受时判断，命中的请求出队时不受域名限速和并发数的限制
+func (d *downloader) isCacheHit(req *Request) bool {
+	c := d.httpCache
+	if c == nil {
+		return false
+	}
+	entry := c.loadMeta(c.key(req))
+	return entry != nil && c.fresh(req, entry)
+}
+
+// fetch 经过HTTP缓存下载：缓存新鲜时直接返回缓存，否则下载并更新缓存，
+// 缓存有 ETag/Last-Modified 时发起条件请求，服务器返回304时使用缓存
+func (d *downloader) fetch(req *Request, sl *slot) (*Response, error) {
+	c := d.httpCache
+	if c == nil {
+		return d.do(req, sl)
+	}
+	key := c.key(req)
+	entry := c.load(key)
+	if entry != nil && c.fresh(req, entry) {
+		return entry.response(req), nil
+	}
+	if entry != nil {
+		if req.Request.Header == nil {
+			req.Request.Header = http.Header{}
+		}
+		header := req.Request.Header
+		if etag := entry.Header.Get("ETag"); etag != "" && header.Get("If-None-Match") == "" {
+			header.Set("If-None-Match", etag)
+			defer header.Del("If-None-Match")
+		}
+		if modified := entry.Header.Get("Last-Modified"); modified != "" && header.Get("If-Modified-Since") == "" {
+			header.Set("If-Modified-Since", modified)
+			defer header.Del("If-Modified-Since")
+		}
+	}
+	res, err := d.do(req, sl)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil && res.StatusCode == http.StatusNotModified {
+		res.close()
+		for k, v := range res.Header {
+			entry.Header[k] = v
+		}
+		entry.Time = time.Now()
+		if err = c.store(key, entry); err != nil {
+			log.Printf("update http cache of %s failed: %v\n", req.URL(), err)
+		}
+		return entry.response(req), nil
+	}
+	if !c.cacheable(req, res.Response) {
+		return res, nil
+	}
+	body := res.Body()
+	if err = res.BodyErr(); errors.Is(err, ErrTooLarge) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, networkError(req, err)
+	}
+	entry = &cacheEntry{
+		URL:        req.URL(),
+		Method:     req.Method(),
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Time:       time.Now(),
+		body:       body,
+	}
+	if err = c.store(key, entry); err != nil {
+		log.Printf("store http cache of %s failed: %v\n", req.URL(), err)
+	}
+	return res, nil
+}
+
+// SetHTTPCache 开启磁盘HTTP缓存，响应以请求指纹为键保存在dir目录下
+func (d *downloader) SetHTTPCache(dir string, policy CachePolicy) {
+	d.httpCache = &httpCache{dir: dir, policy: policy}
+}
+
+// SetHTTPCacheExpiration 设置缓存有效期，超过有效期的缓存视为不存在，0表示永不过期，需先开启HTTP缓存
+func (d *downloader) SetHTTPCacheExpiration(expiration time.Duration) {
+	if d.httpCache != nil {
+		d.httpCache.expiration = expiration
+	}
+}
+
+// SetHTTPCacheIgnoreHTTPCode 设置不缓存的状态码，需先开启HTTP缓存
+func (d *downloader) SetHTTPCacheIgnoreHTTPCode(httpCode ...int) {
+	if d.httpCache != nil {
+		d.httpCache.ignoreCode = append(d.httpCache.ignoreCode, httpCode...)
+	}
+}
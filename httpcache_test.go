@@ -0,0 +1,113 @@
+package gugo
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFreshnessLifetime(t *testing.T) {
+	date := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	format := func(t time.Time) string { return t.Format(http.TimeFormat) }
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"none", http.Header{}, 0},
+		{"max-age", http.Header{"Cache-Control": {"public, max-age=60"}}, time.Minute},
+		{"max-age wins over expires", http.Header{
+			"Cache-Control": {"max-age=60"},
+			"Date":          {format(date)},
+			"Expires":       {format(date.Add(time.Hour))},
+		}, time.Minute},
+		{"expires", http.Header{
+			"Date":    {format(date)},
+			"Expires": {format(date.Add(time.Hour))},
+		}, time.Hour},
+		{"invalid expires", http.Header{
+			"Date":    {format(date)},
+			"Expires": {"0"},
+		}, 0},
+		{"expires without date", http.Header{"Expires": {format(date)}}, 0},
+		{"last-modified heuristic", http.Header{
+			"Date":          {format(date)},
+			"Last-Modified": {format(date.Add(-10 * time.Hour))},
+		}, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := freshnessLifetime(cacheControl(tt.header), tt.header); got != tt.want {
+			t.Errorf("%s: freshnessLifetime = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	cc := cacheControl(http.Header{"Cache-Control": {`No-Cache, max-age="30"`, "private"}})
+	for k, want := range map[string]string{"no-cache": "", "max-age": "30", "private": ""} {
+		if got, ok := cc[k]; !ok || got != want {
+			t.Errorf("cacheControl[%q] = %q, %v, want %q", k, got, ok, want)
+		}
+	}
+}
+
+func TestHTTPCachePost(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+	d := newDownloader()
+	d.SetHTTPCache(t.TempDir(), CacheAlways)
+	for i := 0; i < 2; i++ {
+		r, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("q=gugo"))
+		req := NewRequest(r, func(*Response) {}, nil)
+		res, err := d.fetch(req, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(res.Body()); got != "q=gugo" {
+			t.Errorf("response body = %q, want the request body echoed", got)
+		}
+		if res.Cached() != (i == 1) {
+			t.Errorf("request %d cached = %v", i, res.Cached())
+		}
+		// 下载后请求体仍然保留，用于重试、指纹和断点
+		if got := string(req.Record().Body); got != "q=gugo" {
+			t.Errorf("recorded body = %q, want q=gugo", got)
+		}
+	}
+	if hits != 1 {
+		t.Errorf("server hits = %d, want 1", hits)
+	}
+}
+
+func TestIsCacheHit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = w.Write([]byte("gugo"))
+	}))
+	defer srv.Close()
+	for _, policy := range []CachePolicy{CacheAlways, CacheRFC7234} {
+		d := newDownloader()
+		d.SetHTTPCache(t.TempDir(), policy)
+		r, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		req := NewRequest(r, func(*Response) {}, nil)
+		if d.isCacheHit(req) {
+			t.Fatalf("policy %d: cache hit before the first download", policy)
+		}
+		if _, err := d.fetch(req, nil); err != nil {
+			t.Fatal(err)
+		}
+		// no-cache 的响应在 RFC 7234 策略下需要重新验证，不能跳过限速
+		if got, want := d.isCacheHit(req), policy == CacheAlways; got != want {
+			t.Errorf("policy %d: isCacheHit = %v, want %v", policy, got, want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package gugo
+
+// DownloaderMiddleware 下载器中间件
+// ProcessRequest 按注册顺序调用，ProcessResponse、ProcessError 按注册逆序调用。
+// 三个方法的返回值 (*Request, *Response, error) 约定如下：
+// 1、返回新的请求：中断中间件链，新请求重新放入请求队列(返回原请求视为重新下载)
+// 2、返回响应：ProcessRequest 中直接跳过下载，ProcessError 中视为从错误中恢复，均继续交给 ProcessResponse 处理
+// 3、返回错误：交给 ProcessError 处理，ProcessResponse、ProcessError 返回的错误视为最终下载失败
+// 4、全部为nil：丢弃该请求
+// 特别地，ProcessRequest 返回原请求表示继续交给下一个中间件处理，可以在返回前修改原请求。
+type DownloaderMiddleware interface {
+	ProcessRequest(req *Request) (*Request, *Response, error)
+	ProcessResponse(req *Request, res *Response) (*Request, *Response, error)
+	ProcessError(req *Request, err error) (*Request, *Response, error)
+}
+
+// BaseDownloaderMiddleware 什么都不做的下载器中间件，客户端可嵌入后只实现关心的方法
+type BaseDownloaderMiddleware struct{}
+
+func (BaseDownloaderMiddleware) ProcessRequest(req *Request) (*Request, *Response, error) {
+	return req, nil, nil
+}
+
+func (BaseDownloaderMiddleware) ProcessResponse(req *Request, res *Response) (*Request, *Response, error) {
+	return nil, res, nil
+}
+
+func (BaseDownloaderMiddleware) ProcessError(req *Request, err error) (*Request, *Response, error) {
+	return nil, nil, err
+}
+
+// processRequest 依次调用中间件的 ProcessRequest，全部放行时返回原请求
+func (d *downloader) processRequest(req *Request) (*Request, *Response, error) {
+	for _, m := range d.middlewares {
+		r, res, err := m.ProcessRequest(req)
+		if r == req && res == nil && err == nil {
+			continue
+		}
+		return r, res, err
+	}
+	return req, nil, nil
+}
+
+// processResponse 逆序调用中间件的 ProcessResponse，响应被丢弃或被新的请求替换时关闭响应体
+func (d *downloader) processResponse(req *Request, res *Response) (*Request, *Response, error) {
+	for i := len(d.middlewares) - 1; i >= 0; i-- {
+		r, rs, err := d.middlewares[i].ProcessResponse(req, res)
+		if r != nil || rs == nil || err != nil {
+			res.discard()
+			return r, nil, err
+		}
+		res = rs
+	}
+	return nil, res, nil
+}
+
+// processError 逆序调用中间件的 ProcessError，直到错误被某个中间件处理
+func (d *downloader) processError(req *Request, err error) (*Request, *Response, error) {
+	for i := len(d.middlewares) - 1; i >= 0; i-- {
+		r, res, e := d.middlewares[i].ProcessError(req, err)
+		if r != nil || res != nil || e == nil {
+			return r, res, nil
+		}
+		err = e
+	}
+	return nil, nil, err
+}
+
+// SetDownloaderMiddleware 按顺序注册下载器中间件
+func (d *downloader) SetDownloaderMiddleware(middleware ...DownloaderMiddleware) {
+	d.middlewares = append(d.middlewares, middleware...)
+}
+
+// SpiderMiddleware 爬虫中间件，按注册顺序调用
+// ProcessSpiderInput 在响应交给解析器之前调用，返回错误则跳过解析；
+// ProcessSpiderOutput 在解析器产出新请求(*Request)或数据时调用，返回nil则丢弃，也可以返回修改或替换后的结果。
+// 通过 Response.NativeRequest、Response.Push 产出时 res 为当前响应，通过 GuGo.Request、GuGo.Push 产出时 res 为nil。
+type SpiderMiddleware interface {
+	ProcessSpiderInput(res *Response) error
+	ProcessSpiderOutput(res *Response, result interface{}) interface{}
+}
+
+// BaseSpiderMiddleware 什么都不做的爬虫中间件，客户端可嵌入后只实现关心的方法
+type BaseSpiderMiddleware struct{}
+
+func (BaseSpiderMiddleware) ProcessSpiderInput(res *Response) error {
+	return nil
+}
+
+func (BaseSpiderMiddleware) ProcessSpiderOutput(res *Response, result interface{}) interface{} {
+	return result
+}
+
+// processSpiderInput 依次调用中间件的 ProcessSpiderInput
+func (s *spider) processSpiderInput(res *Response) error {
+	for _, m := range s.middlewares {
+		if err := m.ProcessSpiderInput(res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processSpiderOutput 依次调用中间件的 ProcessSpiderOutput，结果被丢弃时返回nil
+func (s *spider) processSpiderOutput(res *Response, result interface{}) interface{} {
+	for _, m := range s.middlewares {
+		if result = m.ProcessSpiderOutput(res, result); result == nil {
+			return nil
+		}
+	}
+	return result
+}
+
+// SetSpiderMiddleware 按顺序注册爬虫中间件
+func (s *spider) SetSpiderMiddleware(middleware ...SpiderMiddleware) {
+	s.middlewares = append(s.middlewares, middleware...)
+}
@@ -0,0 +1,299 @@
+package gugo
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// funcMiddleware 由函数组成的下载器中间件，未设置的方法放行，并记录调用顺序
+type funcMiddleware struct {
+	name     string
+	calls    *[]string
+	request  func(*Request) (*Request, *Response, error)
+	response func(*Request, *Response) (*Request, *Response, error)
+	err      func(*Request, error) (*Request, *Response, error)
+}
+
+func (m funcMiddleware) ProcessRequest(req *Request) (*Request, *Response, error) {
+	*m.calls = append(*m.calls, m.name+".request")
+	if m.request != nil {
+		return m.request(req)
+	}
+	return req, nil, nil
+}
+
+func (m funcMiddleware) ProcessResponse(req *Request, res *Response) (*Request, *Response, error) {
+	*m.calls = append(*m.calls, m.name+".response")
+	if m.response != nil {
+		return m.response(req, res)
+	}
+	return nil, res, nil
+}
+
+func (m funcMiddleware) ProcessError(req *Request, err error) (*Request, *Response, error) {
+	*m.calls = append(*m.calls, m.name+".error")
+	if m.err != nil {
+		return m.err(req, err)
+	}
+	return nil, nil, err
+}
+
+// closeBody 记录是否被关闭的响应体
+type closeBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *closeBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+// downloadOnce 不经过引擎下载一次请求
+func downloadOnce(d *downloader, req *Request) (*Request, *Response) {
+	concurrent := make(chan struct{}, 1)
+	concurrent <- struct{}{}
+	return d.download(req, nil, concurrent)
+}
+
+func TestDownloaderMiddlewareContract(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	r, _ := http.NewRequest(http.MethodGet, srv.URL+"/other", nil)
+	other := NewRequest(r, nil, nil)
+	stub := &Response{Response: &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("stub"))}}
+	errDenied := errors.New("denied")
+	replaced := &closeBody{Reader: strings.NewReader("replaced")}
+	tests := []struct {
+		name  string
+		path  string
+		a, b  funcMiddleware
+		calls []string
+		req   *Request // 重新放入请求队列的请求
+		body  string   // 交给爬虫解析的响应体，空表示没有响应
+		err   error    // 错误回调收到的错误
+	}{
+		{
+			name:  "pass through",
+			calls: []string{"a.request", "b.request", "b.response", "a.response"},
+			body:  "ok",
+		},
+		{
+			name: "request replaced by a new request",
+			a: funcMiddleware{request: func(*Request) (*Request, *Response, error) {
+				return other, nil, nil
+			}},
+			calls: []string{"a.request"},
+			req:   other,
+		},
+		{
+			name: "request dropped",
+			b: funcMiddleware{request: func(*Request) (*Request, *Response, error) {
+				return nil, nil, nil
+			}},
+			calls: []string{"a.request", "b.request"},
+		},
+		{
+			name: "download skipped by a response",
+			a: funcMiddleware{request: func(*Request) (*Request, *Response, error) {
+				return nil, stub, nil
+			}},
+			calls: []string{"a.request", "b.response", "a.response"},
+			body:  "stub",
+		},
+		{
+			name: "request error recovered",
+			b: funcMiddleware{request: func(*Request) (*Request, *Response, error) {
+				return nil, nil, errDenied
+			}},
+			a: funcMiddleware{err: func(req *Request, err error) (*Request, *Response, error) {
+				return nil, stub, nil
+			}},
+			calls: []string{"a.request", "b.request", "b.error", "a.error", "b.response", "a.response"},
+			body:  "stub",
+		},
+		{
+			name:  "request error not handled",
+			b:     funcMiddleware{request: func(*Request) (*Request, *Response, error) { return nil, nil, errDenied }},
+			calls: []string{"a.request", "b.request", "b.error", "a.error"},
+			err:   errDenied,
+		},
+		{
+			name: "response replaced by a new request",
+			b: funcMiddleware{response: func(req *Request, res *Response) (*Request, *Response, error) {
+				res.Response.Body = replaced
+				return other, nil, nil
+			}},
+			calls: []string{"a.request", "b.request", "b.response"},
+			req:   other,
+		},
+		{
+			name: "response rejected",
+			path: "/missing",
+			b: funcMiddleware{response: func(req *Request, res *Response) (*Request, *Response, error) {
+				if res.StatusCode == http.StatusNotFound {
+					return nil, nil, errDenied
+				}
+				return nil, res, nil
+			}},
+			calls: []string{"a.request", "b.request", "b.response"},
+			err:   errDenied,
+		},
+	}
+	for _, tt := range tests {
+		var calls []string
+		tt.a.name, tt.a.calls = "a", &calls
+		tt.b.name, tt.b.calls = "b", &calls
+		d := newDownloader()
+		d.SetDownloaderMiddleware(tt.a, tt.b)
+		r, _ := http.NewRequest(http.MethodGet, srv.URL+tt.path, nil)
+		var failed error
+		req := NewRequest(r, nil, nil, ErrBack(func(_ *Request, err error) { failed = err }))
+		stub.Request = nil
+		next, res := downloadOnce(d, req)
+		if !reflect.DeepEqual(calls, tt.calls) {
+			t.Errorf("%s: calls = %v, want %v", tt.name, calls, tt.calls)
+		}
+		if next != tt.req {
+			t.Errorf("%s: requeued request = %v, want %v", tt.name, next, tt.req)
+		}
+		if res != nil {
+			if body := string(res.Body()); body != tt.body || res.Request != req {
+				t.Errorf("%s: response body = %q, request = %v, want %q of the original request", tt.name, body, res.Request, tt.body)
+			}
+		} else if tt.body != "" {
+			t.Errorf("%s: response = nil, want %q", tt.name, tt.body)
+		}
+		if !errors.Is(failed, tt.err) {
+			t.Errorf("%s: errBack err = %v, want %v", tt.name, failed, tt.err)
+		}
+	}
+	if !replaced.closed {
+		t.Error("response replaced by a new request is not closed")
+	}
+}
+
+// stubMiddleware ProcessRequest 直接返回构造的响应，跳过下载
+type stubMiddleware struct {
+	BaseDownloaderMiddleware
+	res *Response
+}
+
+func (m stubMiddleware) ProcessRequest(req *Request) (*Request, *Response, error) {
+	return nil, m.res, nil
+}
+
+func TestShortCircuitWithoutBody(t *testing.T) {
+	for _, stub := range []*Response{{Response: &http.Response{StatusCode: http.StatusOK}}, {}} {
+		d := newDownloader()
+		d.SetDownloaderMiddleware(stubMiddleware{res: stub})
+		r, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+		concurrent := make(chan struct{}, 1)
+		concurrent <- struct{}{}
+		_, res := d.download(NewRequest(r, nil, nil), nil, concurrent)
+		if res != stub {
+			t.Fatalf("download returned %v, want the stub response", res)
+		}
+		parsed := false
+		res.parser = func(res *Response) {
+			parsed = true
+			if b, err := io.ReadAll(res.Reader()); err != nil || len(b) != 0 {
+				t.Errorf("Reader = %q, %v, want an empty body", b, err)
+			}
+			for i := 0; i < 2; i++ {
+				if b := res.Body(); len(b) != 0 {
+					t.Errorf("Body = %q, want an empty body", b)
+				}
+			}
+		}
+		s := newSpider()
+		s.concurrentResponse <- struct{}{}
+		s.response(res)
+		if !parsed || s.CompletedCount() != 1 {
+			t.Errorf("parsed = %v, completed = %d, want the response parsed", parsed, s.CompletedCount())
+		}
+	}
+}
+
+// funcSpiderMiddleware 拦截路径为 /blocked 的响应，数据转为大写，丢弃 "drop"，并记录产出时收到的响应
+type funcSpiderMiddleware struct {
+	from map[interface{}]*Response
+}
+
+func (m funcSpiderMiddleware) ProcessSpiderInput(res *Response) error {
+	if res.Request.Request.URL.Path == "/blocked" {
+		return errors.New("blocked")
+	}
+	return nil
+}
+
+func (m funcSpiderMiddleware) ProcessSpiderOutput(res *Response, result interface{}) interface{} {
+	if r, ok := result.(*Request); ok {
+		m.from[r.URL()] = res
+		return result
+	}
+	m.from[result] = res
+	switch result {
+	case "drop":
+		return nil
+	case "a", "b":
+		return strings.ToUpper(result.(string))
+	}
+	return result
+}
+
+func TestSpiderMiddleware(t *testing.T) {
+	g := CreateGuGo()
+	m := funcSpiderMiddleware{from: make(map[interface{}]*Response)}
+	g.SetSpiderMiddleware(m)
+	newResponse := func(path string, parser Parser) (*Response, *closeBody) {
+		r, _ := http.NewRequest(http.MethodGet, "http://example.com"+path, nil)
+		body := &closeBody{Reader: strings.NewReader("")}
+		return &Response{Response: &http.Response{Body: body}, Request: NewRequest(r, parser, nil), engine: g.engine}, body
+	}
+	parsed := 0
+	parser := func(res *Response) {
+		parsed++
+		res.Push("a")
+		res.Push("drop")
+		res.Get("http://example.com/next", func(*Response) {}, nil)
+	}
+	res, _ := newResponse("/", parser)
+	res.Request.depth = 2
+	blocked, body := newResponse("/blocked", parser)
+	for _, r := range []*Response{res, blocked} {
+		g.spider.concurrentResponse <- struct{}{}
+		g.spider.response(r)
+	}
+	g.Push("b")
+	if parsed != 1 || g.spider.InterceptCount() != 1 || !body.closed {
+		t.Errorf("parsed = %d, intercepted = %d, closed = %v, want the blocked response skipped and closed",
+			parsed, g.spider.InterceptCount(), body.closed)
+	}
+	want := map[interface{}]*Response{"a": res, "drop": res, "http://example.com/next": res, "b": nil}
+	if !reflect.DeepEqual(m.from, want) {
+		t.Errorf("ProcessSpiderOutput responses = %v, want %v", m.from, want)
+	}
+	var items []interface{}
+	for len(items) < 2 {
+		items = append(items, <-g.Pull())
+	}
+	if len(g.Pull()) != 0 || !(reflect.DeepEqual(items, []interface{}{"A", "B"}) || reflect.DeepEqual(items, []interface{}{"B", "A"})) {
+		t.Errorf("pulled items = %v, want A and B", items)
+	}
+	records := g.pendingRecords()
+	if len(records) != 1 || records[0].Depth != 3 {
+		t.Fatalf("pending requests = %v, want the follow-up request at depth 3", records)
+	}
+}
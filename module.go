@@ -2,6 +2,7 @@ package gugo
 
 import (
 	"sync/atomic"
+	"time"
 )
 
 type module struct {
@@ -11,6 +12,10 @@ type module struct {
 	interceptCount uint64 // 代表请求被拦截的计数
 	completedCount uint64 // 代表请求成功完成的计数
 	handlingNumber uint64 // 代表请求实时处理的计数
+	downloadCount  uint64 // 代表请求下载次数的计数(含重试)
+	errorCount     uint64 // 代表请求下载出错的计数(含重试)
+	latencyTotal   uint64 // 代表请求下载耗时的累计(纳秒)
+	tripCount      uint64 // 代表域名熔断器打开的计数
 }
 
 func (m *module) IncrCalledCount() {
@@ -61,6 +66,22 @@ func (m *module) DecrHandlingNumber() {
 	atomic.AddUint64(&m.handlingNumber, ^uint64(0))
 }
 
+func (m *module) IncrDownloadCount() {
+	atomic.AddUint64(&m.downloadCount, 1)
+}
+
+func (m *module) IncrErrorCount() {
+	atomic.AddUint64(&m.errorCount, 1)
+}
+
+func (m *module) IncrTripCount() {
+	atomic.AddUint64(&m.tripCount, 1)
+}
+
+func (m *module) AddLatency(latency time.Duration) {
+	atomic.AddUint64(&m.latencyTotal, uint64(latency))
+}
+
 func (m *module) CalledCount() uint64 {
 	return atomic.LoadUint64(&m.calledCount)
 }
@@ -85,6 +106,34 @@ func (m *module) HandlingNumber() uint64 {
 	return atomic.LoadUint64(&m.handlingNumber)
 }
 
+func (m *module) DownloadCount() uint64 {
+	return atomic.LoadUint64(&m.downloadCount)
+}
+
+func (m *module) ErrorCount() uint64 {
+	return atomic.LoadUint64(&m.errorCount)
+}
+
+func (m *module) TripCount() uint64 {
+	return atomic.LoadUint64(&m.tripCount)
+}
+
+// ErrorRate 下载出错率
+func (m *module) ErrorRate() float64 {
+	if n := m.DownloadCount(); n > 0 {
+		return float64(m.ErrorCount()) / float64(n)
+	}
+	return 0
+}
+
+// AverageLatency 平均下载耗时
+func (m *module) AverageLatency() time.Duration {
+	if n := m.DownloadCount(); n > 0 {
+		return time.Duration(atomic.LoadUint64(&m.latencyTotal) / n)
+	}
+	return 0
+}
+
 func (m *module) Clear() {
 	atomic.StoreUint64(&m.calledCount, 0)
 	atomic.StoreUint64(&m.failedCount, 0)
@@ -92,4 +141,36 @@ func (m *module) Clear() {
 	atomic.StoreUint64(&m.interceptCount, 0)
 	atomic.StoreUint64(&m.completedCount, 0)
 	atomic.StoreUint64(&m.handlingNumber, 0)
+	atomic.StoreUint64(&m.downloadCount, 0)
+	atomic.StoreUint64(&m.errorCount, 0)
+	atomic.StoreUint64(&m.latencyTotal, 0)
+	atomic.StoreUint64(&m.tripCount, 0)
+}
+
+// snapshot 计数快照，用于断点续爬，不包含实时处理的计数
+func (m *module) snapshot() map[string]uint64 {
+	return map[string]uint64{
+		"called":    m.CalledCount(),
+		"failed":    m.FailedCount(),
+		"accepted":  m.AcceptedCount(),
+		"intercept": m.InterceptCount(),
+		"completed": m.CompletedCount(),
+		"download":  m.DownloadCount(),
+		"error":     m.ErrorCount(),
+		"latency":   atomic.LoadUint64(&m.latencyTotal),
+		"trip":      m.TripCount(),
+	}
+}
+
+// restore 从计数快照恢复
+func (m *module) restore(stats map[string]uint64) {
+	atomic.StoreUint64(&m.calledCount, stats["called"])
+	atomic.StoreUint64(&m.failedCount, stats["failed"])
+	atomic.StoreUint64(&m.acceptedCount, stats["accepted"])
+	atomic.StoreUint64(&m.interceptCount, stats["intercept"])
+	atomic.StoreUint64(&m.completedCount, stats["completed"])
+	atomic.StoreUint64(&m.downloadCount, stats["download"])
+	atomic.StoreUint64(&m.errorCount, stats["error"])
+	atomic.StoreUint64(&m.latencyTotal, stats["latency"])
+	atomic.StoreUint64(&m.tripCount, stats["trip"])
 }
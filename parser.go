@@ -0,0 +1,36 @@
+package gugo
+
+import (
+	"fmt"
+)
+
+// RegisterParser 按名字注册解析器，请求可以通过 Callback 选项以名字引用解析器，
+// 请求序列化时也优先使用注册的名字
+func (s *spider) RegisterParser(name string, parser Parser) {
+	s.pmu.Lock()
+	defer s.pmu.Unlock()
+	s.parsers[name] = parser
+	s.names[parserName(parser)] = name
+}
+
+// callbackName 解析器注册的名字，未注册时返回空
+func (s *spider) callbackName(parser Parser) string {
+	s.pmu.RLock()
+	defer s.pmu.RUnlock()
+	return s.names[parserName(parser)]
+}
+
+// resolve 为以名字引用解析器的请求查找解析器
+func (s *spider) resolve(r *Request) error {
+	if r.parser != nil {
+		return nil
+	}
+	s.pmu.RLock()
+	defer s.pmu.RUnlock()
+	parser, ok := s.parsers[r.callback]
+	if !ok {
+		return &RequestError{Kind: ErrCallback, URL: r.URL(), Err: fmt.Errorf("%q", r.callback)}
+	}
+	r.parser = parser
+	return nil
+}
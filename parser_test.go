@@ -0,0 +1,47 @@
+package gugo
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func registeredParser(*Response) {}
+
+func TestParserRegistry(t *testing.T) {
+	e := newEngine()
+	e.RegisterParser("detail", registeredParser)
+	r, _ := http.NewRequest(http.MethodGet, "http://example.com/1", nil)
+	req := NewRequest(r, registeredParser, nil)
+	e.emit(nil, req)
+	// 以函数引用已注册的解析器时，请求记录注册的名字
+	if req.Callback() != "detail" || req.Record().Callback != "detail" {
+		t.Errorf("callback = %q, record callback = %q, want detail", req.Callback(), req.Record().Callback)
+	}
+	r, _ = http.NewRequest(http.MethodGet, "http://example.com/2", nil)
+	req = NewRequest(r, nil, nil, Callback("detail"))
+	if err := e.resolve(req); err != nil || parserName(req.parser) != parserName(registeredParser) {
+		t.Errorf("resolve = %v, parser = %s, want the registered parser", err, parserName(req.parser))
+	}
+}
+
+func TestUnknownCallback(t *testing.T) {
+	e := newEngine()
+	r, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	var failed error
+	req := NewRequest(r, nil, nil, Callback("missing"), ErrBack(func(_ *Request, err error) { failed = err }))
+	e.ask(req)
+	got, _ := e.frontier.pop(e.slot)
+	if got != req {
+		t.Fatalf("pop = %v, want the request", got)
+	}
+	e.concurrentRequest <- struct{}{}
+	e.dispatch(req)
+	if !errors.Is(failed, ErrCallback) {
+		t.Errorf("errBack err = %v, want ErrCallback", failed)
+	}
+	if len(e.concurrentRequest) != 0 || len(e.pendingRecords()) != 0 || e.downloader.FailedCount() != 1 {
+		t.Errorf("permits = %d, pending = %d, failed = %d, want the request released and counted as failed",
+			len(e.concurrentRequest), len(e.pendingRecords()), e.downloader.FailedCount())
+	}
+}
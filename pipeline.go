@@ -1,5 +1,9 @@
 package gugo
 
+import (
+	"sync/atomic"
+)
+
 const (
 	PipelineBufCap     = 1 << 12 // 默认数据队列容量
 	ConcurrentPipeline = 1 << 10 // 默认数据处理的并发量
@@ -8,6 +12,7 @@ const (
 type pipeline struct {
 	pipeBuf            chan interface{} // 数据队列
 	concurrentPipeline chan struct{}    // 数据并发控制
+	pushing            int64            // 正在发送到数据队列的数量
 }
 
 func newPipeline() *pipeline {
@@ -17,9 +22,13 @@ func newPipeline() *pipeline {
 	}
 }
 
-// push 接受客户端推送的数据
+// push 接受客户端推送的数据，发送前计数，数据队列在没有正在发送的数据时才会被关闭
 func (p *pipeline) push(item interface{}) {
-	go func() { p.pipeBuf <- item }()
+	atomic.AddInt64(&p.pushing, 1)
+	go func() {
+		p.pipeBuf <- item
+		atomic.AddInt64(&p.pushing, -1)
+	}()
 }
 
 // pull 客户端拉取数据
@@ -27,9 +36,9 @@ func (p *pipeline) pull() chan interface{} {
 	return p.pipeBuf
 }
 
-// Empty 数据管道是否空
+// Empty 数据管道是否空：没有正在发送的数据且数据队列为空
 func (p *pipeline) Empty() bool {
-	return len(p.pipeBuf) == 0
+	return atomic.LoadInt64(&p.pushing) == 0 && len(p.pipeBuf) == 0
 }
 
 // SetPipelineBufCap 设置数据队列容量
@@ -6,10 +6,22 @@ import (
 	"syscall"
 )
 
-// TODO: 实现一个断点续爬的功能
-
 type doSomething func()
 
+// Watch 监听中断信号，收到信号后执行 something，默认监听 SIGINT、SIGTERM
+func Watch(something doSomething, sig ...os.Signal) {
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig...)
+	go func() {
+		<-ch
+		signal.Stop(ch)
+		something()
+	}()
+}
+
 func BreakPoint(breaking chan uint8, something doSomething) {
 	go keepWatchOn(breaking, something)
 }
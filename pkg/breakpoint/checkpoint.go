@@ -0,0 +1,111 @@
+package breakpoint
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const (
+	RequestsFile = "requests.jsonl" // 未完成请求文件，每行一个请求
+	FilterFile   = "filter.bin"     // 去重过滤器文件
+	StateFile    = "state.json"     // 重试计数和统计信息文件
+	CookiesFile  = "cookies.json"   // 会话 Cookie 文件
+)
+
+// Checkpoint 断点：爬虫在某一时刻的可恢复状态
+type Checkpoint struct {
+	Requests [][]byte                     `json:"-"`     // 未完成的请求，每个请求已序列化为一行JSON
+	Filter   []byte                       `json:"-"`     // 去重过滤器的二进制数据
+	Cookies  []byte                       `json:"-"`     // 会话 Cookie 的JSON数据
+	Retry    map[string]uint32            `json:"retry"` // 请求指纹对应的重试次数
+	Stats    map[string]map[string]uint64 `json:"stats"` // 各组件的统计信息
+}
+
+// Save 保存断点到目录：所有文件先写入临时目录，再整体替换原目录，
+// 避免中途退出留下新旧混杂的断点，替换过程中退出时 Load 读取上一次的断点
+func Save(dir string, cp *Checkpoint) error {
+	dir = filepath.Clean(dir)
+	tmp, old := dir+".tmp", dir+".old"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return err
+	}
+	state, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	var requests bytes.Buffer
+	for _, r := range cp.Requests {
+		requests.Write(r)
+		requests.WriteByte('\n')
+	}
+	files := map[string][]byte{
+		RequestsFile: requests.Bytes(),
+		FilterFile:   cp.Filter,
+		StateFile:    state,
+		CookiesFile:  cp.Cookies,
+	}
+	for name, data := range files {
+		if err = os.WriteFile(filepath.Join(tmp, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	if _, err = os.Stat(dir); err == nil {
+		if err = os.RemoveAll(old); err != nil {
+			return err
+		}
+		if err = os.Rename(dir, old); err != nil {
+			return err
+		}
+	}
+	if err = os.Rename(tmp, dir); err != nil {
+		return err
+	}
+	return os.RemoveAll(old)
+}
+
+// Load 从目录加载断点，目录不存在时尝试读取替换过程中保留的上一次断点
+func Load(dir string) (*Checkpoint, error) {
+	dir = filepath.Clean(dir)
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		if _, err = os.Stat(dir + ".old"); err == nil {
+			dir += ".old"
+		}
+	}
+	cp := &Checkpoint{}
+	state, err := os.ReadFile(filepath.Join(dir, StateFile))
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(state, cp); err != nil {
+		return nil, err
+	}
+	if cp.Filter, err = os.ReadFile(filepath.Join(dir, FilterFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if cp.Cookies, err = os.ReadFile(filepath.Join(dir, CookiesFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(dir, RequestsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Bytes(); len(bytes.TrimSpace(line)) > 0 {
+			cp.Requests = append(cp.Requests, append([]byte(nil), line...))
+		}
+	}
+	return cp, scanner.Err()
+}
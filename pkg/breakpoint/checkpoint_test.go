@@ -0,0 +1,63 @@
+package breakpoint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "checkpoint")
+	for _, requests := range [][][]byte{
+		{[]byte(`{"url":"http://a.com"}`)},
+		{[]byte(`{"url":"http://b.com"}`), []byte(`{"url":"http://c.com"}`)},
+	} {
+		cp := &Checkpoint{
+			Requests: requests,
+			Filter:   []byte{1, 2, 3},
+			Retry:    map[string]uint32{"ab": 1},
+			Stats:    map[string]map[string]uint64{"scheduler": {"called": 3}},
+		}
+		if err := Save(dir, cp); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Load(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got.Requests, cp.Requests) || !reflect.DeepEqual(got.Filter, cp.Filter) ||
+			!reflect.DeepEqual(got.Retry, cp.Retry) || !reflect.DeepEqual(got.Stats, cp.Stats) {
+			t.Fatalf("Load = %+v, want %+v", got, cp)
+		}
+	}
+	for _, name := range []string{dir + ".tmp", dir + ".old"} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("%s is left behind: %v", name, err)
+		}
+	}
+}
+
+func TestLoadDuringSwap(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "checkpoint")
+	if err := Save(dir, &Checkpoint{Requests: [][]byte{[]byte(`{}`)}}); err != nil {
+		t.Fatal(err)
+	}
+	// 模拟替换目录时退出：原目录已经移走，新目录还没有就位
+	if err := os.Rename(dir, dir+".old"); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cp.Requests) != 1 {
+		t.Fatalf("requests = %d, want the previous checkpoint", len(cp.Requests))
+	}
+	if err = Save(dir, &Checkpoint{}); err != nil {
+		t.Fatal(err)
+	}
+	if cp, err = Load(dir); err != nil || len(cp.Requests) != 0 {
+		t.Fatalf("Load after save = %v, %v, want the new checkpoint", cp, err)
+	}
+}
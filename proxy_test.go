@@ -0,0 +1,102 @@
+package gugo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProxyPick(t *testing.T) {
+	pool, err := newProxyPool(ProxyRoundRobin, "http://a:1", "http://b:1", "socks5://c:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, pool.pick().url.Host)
+	}
+	if want := "a:1 b:1 c:1 a:1"; strings.Join(got, " ") != want {
+		t.Errorf("round robin = %s, want %s", strings.Join(got, " "), want)
+	}
+	// 被禁用的代理不参与选择
+	pool.proxies[1].until = time.Now().Add(time.Hour)
+	got = got[:0]
+	for i := 0; i < 3; i++ {
+		got = append(got, pool.pick().url.Host)
+	}
+	if want := "c:1 a:1 c:1"; strings.Join(got, " ") != want {
+		t.Errorf("round robin with a banned proxy = %s, want %s", strings.Join(got, " "), want)
+	}
+	pool.strategy = ProxyLeastUsed
+	if p := pool.pick(); p.url.Host != "c:1" && p.url.Host != "a:1" {
+		t.Errorf("least used = %s", p.url.Host)
+	}
+	for _, p := range pool.proxies {
+		p.until = time.Now().Add(time.Hour)
+	}
+	if p := pool.pick(); p != nil {
+		t.Errorf("pick = %s, want nil when all proxies are banned", p.url.Host)
+	}
+	if _, err = newProxyPool(ProxyRandom, "ftp://a:1"); err == nil {
+		t.Error("ftp proxy should be rejected")
+	}
+}
+
+func TestProxyBan(t *testing.T) {
+	pool, _ := newProxyPool(ProxyRoundRobin, "http://a:1")
+	pool.maxFailures, pool.banTime = 2, time.Hour
+	p := pool.proxies[0]
+	pool.feedback(p, true)
+	pool.feedback(p, false)
+	pool.feedback(p, true)
+	if pool.pick() == nil {
+		t.Fatal("a success should offset a failure")
+	}
+	pool.feedback(p, true)
+	if pool.pick() != nil {
+		t.Fatal("proxy should be banned after 2 failures")
+	}
+}
+
+func TestProxyFeedback(t *testing.T) {
+	// 代理正常转发，目标网站返回503
+	proxySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer proxySrv.Close()
+	d := newDownloader()
+	if err := d.SetProxyPool(ProxyRoundRobin, proxySrv.URL); err != nil {
+		t.Fatal(err)
+	}
+	d.SetProxyBan(1, time.Hour)
+	p := d.proxyPool.proxies[0]
+	r, _ := http.NewRequest(http.MethodGet, "http://target.invalid/", nil)
+	if _, err := d.do(NewRequest(r, nil, nil), nil); !errors.Is(err, ErrRetryHTTPCode) {
+		t.Fatalf("err = %v, want ErrRetryHTTPCode", err)
+	}
+	if p.used != 1 || p.failures != 0 || !p.until.IsZero() {
+		t.Errorf("proxy used, failures = %d, %d, want the target status not counted", p.used, p.failures)
+	}
+	// 客户端自己的 Transport 不使用代理池
+	d.SetClient(&http.Client{Transport: &http.Transport{}})
+	r, _ = http.NewRequest(http.MethodGet, proxySrv.URL, nil)
+	if _, err := d.do(NewRequest(r, nil, nil), nil); !errors.Is(err, ErrRetryHTTPCode) {
+		t.Fatalf("err = %v, want ErrRetryHTTPCode", err)
+	}
+	if p.used != 1 {
+		t.Errorf("proxy used = %d, want the pool skipped", p.used)
+	}
+	// 代理无法连接时计为失败并禁用
+	proxySrv.Close()
+	d.SetClient(&http.Client{})
+	r, _ = http.NewRequest(http.MethodGet, "http://target.invalid/", nil)
+	if _, err := d.do(NewRequest(r, nil, nil), nil); !errors.Is(err, ErrNetwork) {
+		t.Fatalf("err = %v, want ErrNetwork", err)
+	}
+	if p.until.IsZero() {
+		t.Error("unreachable proxy should be banned")
+	}
+}
@@ -0,0 +1,189 @@
+package gugo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"reflect"
+	"runtime"
+)
+
+// RequestRecord 请求的序列化格式，解析器以名字引用，可以按 JSONL 格式每行保存一个请求。
+// 元数据经过JSON序列化后，数字统一变为 float64。
+type RequestRecord struct {
+	Method   string                 `json:"method"`
+	URL      string                 `json:"url"`
+	Header   http.Header            `json:"header,omitempty"`
+	Body     []byte                 `json:"body,omitempty"`
+	Meta     map[string]interface{} `json:"meta,omitempty"`
+	Priority int                    `json:"priority,omitempty"`
+	Depth    int                    `json:"depth,omitempty"`
+	Callback string                 `json:"callback"`
+	Session  string                 `json:"session,omitempty"`
+}
+
+// Record 请求转换为序列化格式，无法序列化的元数据会被忽略
+func (r *Request) Record() *RequestRecord {
+	record := r.snapshot()
+	record.filterMeta()
+	return record
+}
+
+// snapshot 请求当前的序列化格式，请求头为副本，元数据尚未检查能否序列化
+func (r *Request) snapshot() *RequestRecord {
+	record := &RequestRecord{
+		Method:   r.Method(),
+		URL:      r.URL(),
+		Header:   r.Request.Header.Clone(),
+		Body:     r.Body(),
+		Meta:     r.meta,
+		Priority: r.priority,
+		Depth:    r.depth,
+		Callback: r.callback,
+		Session:  r.session,
+	}
+	if record.Callback == "" {
+		record.Callback = parserName(r.parser)
+	}
+	return record
+}
+
+// filterMeta 去掉无法序列化的元数据
+func (rr *RequestRecord) filterMeta() {
+	meta := rr.Meta
+	rr.Meta = nil
+	for k, v := range meta {
+		if _, err := json.Marshal(v); err != nil {
+			log.Printf("%s meta %s is not serializable: %v\n", rr.URL, k, err)
+			continue
+		}
+		if rr.Meta == nil {
+			rr.Meta = make(map[string]interface{})
+		}
+		rr.Meta[k] = v
+	}
+}
+
+// Request 序列化格式转换为请求，parser 为 Callback 对应的解析器，
+// parser 为nil时，请求被分发下载时按 Callback 查找注册的解析器
+func (rr *RequestRecord) Request(parser Parser) (*Request, error) {
+	r, err := http.NewRequest(rr.Method, rr.URL, bytes.NewReader(rr.Body))
+	if err != nil {
+		return nil, err
+	}
+	if rr.Header != nil {
+		r.Header = rr.Header
+	}
+	return &Request{
+		Request:  r,
+		parser:   parser,
+		callback: rr.Callback,
+		meta:     rr.Meta,
+		priority: rr.Priority,
+		depth:    rr.Depth,
+		session:  rr.Session,
+	}, nil
+}
+
+// RequestWriter 按 JSONL 格式写入请求
+type RequestWriter struct {
+	w *bufio.Writer
+}
+
+func NewRequestWriter(w io.Writer) *RequestWriter {
+	return &RequestWriter{w: bufio.NewWriter(w)}
+}
+
+// Write 写入一个请求，占一行
+func (rw *RequestWriter) Write(r *Request) error {
+	b, err := json.Marshal(r.Record())
+	if err != nil {
+		return err
+	}
+	if _, err = rw.w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return rw.w.Flush()
+}
+
+// RequestReader 按 JSONL 格式读取请求
+type RequestReader struct {
+	dec *json.Decoder
+}
+
+func NewRequestReader(r io.Reader) *RequestReader {
+	return &RequestReader{dec: json.NewDecoder(r)}
+}
+
+// Read 读取一个请求的序列化格式，读取完毕时返回 io.EOF
+func (rr *RequestReader) Read() (*RequestRecord, error) {
+	record := &RequestRecord{}
+	if err := rr.dec.Decode(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// parserName 解析器的函数名，同一程序多次运行保持不变
+func parserName(p Parser) string {
+	if p == nil {
+		return ""
+	}
+	return runtime.FuncForPC(reflect.ValueOf(p).Pointer()).Name()
+}
+
+// namedParsers 解析器按函数名索引
+func namedParsers(parsers []Parser) map[string]Parser {
+	named := make(map[string]Parser, len(parsers))
+	for _, p := range parsers {
+		named[parserName(p)] = p
+	}
+	return named
+}
+
+// SaveRequests 按 JSONL 格式追加保存请求到文件
+func (e *engine) SaveRequests(path string, requests ...*Request) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := NewRequestWriter(f)
+	for _, r := range requests {
+		if err = w.Write(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replay 从 JSONL 文件读取请求，请求同样会经过爬虫中间件和调度器的过滤。
+// 请求的解析器优先按函数名从 parsers 中匹配，其次按名字查找注册的解析器。
+func (e *engine) Replay(path string, parsers ...Parser) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	named := namedParsers(parsers)
+	rr := NewRequestReader(f)
+	for {
+		record, err := rr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		r, err := record.Request(named[record.Callback])
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		e.emit(nil, r)
+	}
+}
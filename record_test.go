@@ -0,0 +1,92 @@
+package gugo
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func recordParser(*Response) {}
+
+func TestRequestRecordRoundTrip(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodPost, "http://example.com/search?q=1", strings.NewReader("q=gugo"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	meta := map[string]interface{}{"page": 2, "tags": []string{"a"}, "ch": make(chan int)}
+	req := NewRequest(r, recordParser, meta, Priority(5), Session("user"))
+	req.depth = 1
+	var buf bytes.Buffer
+	w := NewRequestWriter(&buf)
+	for _, r := range []*Request{req, req} {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Fatalf("lines = %d, want one request per line", n)
+	}
+	// 写入后原请求的请求体仍然可以读取
+	if got := string(req.Body()); got != "q=gugo" {
+		t.Errorf("request body after Write = %q", got)
+	}
+	rr := NewRequestReader(&buf)
+	for i := 0; i < 2; i++ {
+		record, err := rr.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := record.Request(recordParser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Method() != http.MethodPost || got.URL() != req.URL() || string(got.Body()) != "q=gugo" {
+			t.Errorf("request = %s %s %q", got.Method(), got.URL(), got.Body())
+		}
+		if got.Request.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			t.Errorf("header = %v", got.Request.Header)
+		}
+		wantMeta := map[string]interface{}{"page": float64(2), "tags": []interface{}{"a"}}
+		if !reflect.DeepEqual(got.Meta(), wantMeta) {
+			t.Errorf("meta = %v, want %v", got.Meta(), wantMeta)
+		}
+		if got.Priority() != 5 || got.Depth() != 1 || got.session != "user" || got.Callback() != parserName(recordParser) {
+			t.Errorf("priority, depth, session, callback = %d, %d, %q, %q",
+				got.Priority(), got.Depth(), got.session, got.Callback())
+		}
+	}
+	if _, err := rr.Read(); err != io.EOF {
+		t.Errorf("Read at the end = %v, want io.EOF", err)
+	}
+}
+
+func TestSaveAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.jsonl")
+	e := newEngine()
+	var requests []*Request
+	for _, u := range []string{"http://example.com/1", "http://example.com/2"} {
+		r, _ := http.NewRequest(http.MethodGet, u, nil)
+		requests = append(requests, NewRequest(r, recordParser, nil))
+	}
+	if err := e.SaveRequests(path, requests[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SaveRequests(path, requests[1]); err != nil {
+		t.Fatal(err)
+	}
+	replayed := newEngine()
+	if err := replayed.Replay(path, recordParser); err != nil {
+		t.Fatal(err)
+	}
+	if n := replayed.frontier.len(); n != 2 {
+		t.Fatalf("replayed requests = %d, want 2 appended requests", n)
+	}
+	for i := 0; i < 2; i++ {
+		r, _ := replayed.frontier.pop(replayed.slot)
+		if r == nil || r.parser == nil {
+			t.Fatalf("replayed request %d = %v, want a request with its parser", i, r)
+		}
+	}
+}
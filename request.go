@@ -1,50 +1,131 @@
 package gugo
 
 import (
+	"bytes"
 	"github.com/xiaogogonuo/gugo/pkg/crypto"
 	"io"
 	"net/http"
+	"time"
 	"unsafe"
 )
 
 type Parser func(*Response)
 
-type request struct {
+type Request struct {
 	*http.Request
-	parser Parser
-	meta   map[string]interface{}
+	parser          Parser
+	callback        string // 解析器注册的名字
+	meta            map[string]interface{}
+	priority        int // 优先级，数值越大越优先
+	depth           int // 深度，初始请求为0，解析响应产生的请求为响应深度加1
+	errBack         func(*Request, error)
+	session         string        // 会话的名字
+	timeout         time.Duration // 读写超时时间，0表示使用下载器的设置
+	downloadTimeout time.Duration // 下载总时间，0表示使用下载器的设置
+	maxSize         int64         // 响应体最大字节数，0表示使用下载器的设置
+	// retryDelay 重新入队前需要等待的时间
+	retryDelay time.Duration
+	// cacheHit 被接受时命中新鲜的HTTP缓存，出队和下载时不占用域名下载槽
+	cacheHit bool
 }
 
-func (r *request) Valid() bool {
-	return r.Request != nil && r.Request.URL != nil && r.parser != nil
+// RequestOption 请求选项
+type RequestOption func(*Request)
+
+// Callback 以注册的名字引用解析器，此时创建请求的 parser 参数可以为nil，
+// 名字在请求被分发下载时才查找，未注册的名字会导致请求失败
+func Callback(name string) RequestOption {
+	return func(r *Request) {
+		r.callback = name
+	}
+}
+
+// Priority 请求的优先级，数值越大越优先，默认为0
+func Priority(priority int) RequestOption {
+	return func(r *Request) {
+		r.priority = priority
+	}
+}
+
+// ErrBack 请求的错误回调，请求被过滤、解析器名字未注册或最终下载失败时调用，
+// 错误通常为 *RequestError，可以通过 errors.Is 判断错误类型，如 ErrTimeout
+func ErrBack(errBack func(*Request, error)) RequestOption {
+	return func(r *Request) {
+		r.errBack = errBack
+	}
+}
+
+// NewRequest 创建请求，用于中间件替换请求或按 JSONL 格式保存请求
+func NewRequest(r *http.Request, parser Parser, meta map[string]interface{}, opts ...RequestOption) *Request {
+	req := &Request{Request: r, parser: parser, meta: meta}
+	for _, opt := range opts {
+		opt(req)
+	}
+	return req
+}
+
+func (r *Request) Valid() bool {
+	return r.Request != nil && r.Request.URL != nil && (r.parser != nil || r.callback != "")
 }
 
-func (r *request) URL() string {
+// Callback 解析器注册的名字
+func (r *Request) Callback() string {
+	return r.callback
+}
+
+func (r *Request) URL() string {
 	return r.Request.URL.String()
 }
 
-func (r *request) Host() string {
+func (r *Request) Host() string {
 	return r.Request.Host
 }
 
-func (r *request) Body() []byte {
+func (r *Request) Meta() map[string]interface{} {
+	return r.meta
+}
+
+func (r *Request) Body() []byte {
 	if r.Request.Body == nil {
 		return []byte{}
 	}
 	body, _ := io.ReadAll(r.Request.Body)
+	_ = r.Request.Body.Close()
+	r.Request.Body = io.NopCloser(bytes.NewReader(body))
 	return body
 }
 
-func (r *request) Method() string {
+func (r *Request) Method() string {
 	return r.Request.Method
 }
 
-func (r *request) Schema() string {
+func (r *Request) Priority() int {
+	return r.priority
+}
+
+// Depth 请求的深度，初始请求为0，通过 Response.Get、Response.NativeRequest、Response.Follow 产生的请求为响应深度加1
+func (r *Request) Depth() int {
+	return r.depth
+}
+
+// fail 请求失败，调用错误回调
+func (r *Request) fail(err error) {
+	if r.errBack != nil {
+		r.errBack(r, err)
+	}
+}
+
+// SetPriority 设置请求的优先级，只对尚未入队的请求生效
+func (r *Request) SetPriority(priority int) {
+	r.priority = priority
+}
+
+func (r *Request) Schema() string {
 	return r.Request.URL.Scheme
 }
 
 // FingerPrint 请求指纹：sha1(请求体+请求URL+请求方法)
-func (r *request) FingerPrint() []byte {
+func (r *Request) FingerPrint() []byte {
 	finger := r.Body()
 	url, method := r.URL(), r.Method()
 	finger = append(finger, *(*[]byte)(unsafe.Pointer(&url))...)
@@ -53,7 +134,7 @@ func (r *request) FingerPrint() []byte {
 }
 
 // FingerPrintS 请求指纹字符串
-func (r *request) FingerPrintS() string {
+func (r *Request) FingerPrintS() string {
 	finger := r.FingerPrint()
 	return *(*string)(unsafe.Pointer(&finger))
 }
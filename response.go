@@ -1,42 +1,126 @@
 package gugo
 
 import (
+	"bytes"
+	"encoding/json"
 	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding"
 	"io"
 	"net/http"
-	"strings"
-	"unsafe"
+	"net/url"
+	"os"
 )
 
 type Response struct {
 	*http.Response
-	*request
+	*Request
+	engine   *engine
+	cached   bool              // 是否来自HTTP缓存
+	abort    func(error)       // 读取响应体时超过大小限制的处理
+	body     []byte            // 缓存的响应体
+	buffered bool              // 响应体是否已经读取并缓存
+	bodyErr  error             // 读取响应体时的错误
+	doc      *html.Node        // 解析后的HTML文档
+	document *goquery.Document // 包装解析后HTML文档的 goquery 文档
+	// 字符编码
+	encoding        encoding.Encoding
+	encodingName    string
+	defaultEncoding string   // 无法判断编码时使用的默认编码
+	text            *string  // 转码后的文本
+	baseURL         *url.URL // 解析相对链接的基准URL
 }
 
 func (r *Response) Valid() bool {
 	return r.Response != nil && r.Response.Body != nil
 }
 
+// Cached 响应是否来自HTTP缓存
+func (r *Response) Cached() bool {
+	return r.cached
+}
+
+// Body 响应体，第一次调用时读取并缓存，之后可以重复调用，中间件和解析器共享同一份数据，不要修改返回值，
+// 中间件构造的响应没有响应体时按空响应体处理
 func (r *Response) Body() []byte {
-	body, _ := io.ReadAll(r.Response.Body)
-	r.close()
-	return body
+	if !r.buffered {
+		body, err := io.ReadAll(responseReader{res: r})
+		r.discard()
+		r.setBody(body)
+		r.bodyErr = err
+	}
+	return r.body
+}
+
+// BodyErr 读取响应体时的错误，如响应体超过大小限制
+func (r *Response) BodyErr() error {
+	return r.bodyErr
+}
+
+// setBody 设置已经读取的响应体
+func (r *Response) setBody(body []byte) {
+	r.body, r.buffered = body, true
+	if r.Response != nil {
+		r.Response.Body = io.NopCloser(bytes.NewReader(body))
+	}
+}
+
+// Reader 流式读取响应体，不在内存中缓存，适合下载大文件，只能在解析器中读取一次，
+// 响应体超过大小限制时读取出错，放弃下载并调用请求的错误回调；响应体已经缓存时读取缓存
+func (r *Response) Reader() io.Reader {
+	if r.buffered {
+		return bytes.NewReader(r.body)
+	}
+	return responseReader{res: r}
+}
+
+// JSON 响应体按JSON解码到v
+func (r *Response) JSON(v interface{}) error {
+	return json.Unmarshal(r.Body(), v)
+}
+
+// SaveTo 流式保存响应体到文件，返回写入的字节数，出错时删除文件
+func (r *Response) SaveTo(path string) (int64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r.Reader())
+	r.discard()
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		_ = os.Remove(path)
+	}
+	return n, err
+}
+
+// Get 在当前响应中发起简易版GET请求，同 NativeRequest。
+// Response 内嵌的 *Request 字段占用了 Request 这个名字，因此命名为 Get
+func (r *Response) Get(url string, parser Parser, meta map[string]interface{}, opts ...RequestOption) {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	r.NativeRequest(req, parser, meta, opts...)
 }
 
-func (r *Response) Text() string {
-	body := r.Body()
-	return *(*string)(unsafe.Pointer(&body))
+// NativeRequest 在当前响应中发起原生请求，请求经过爬虫中间件，中间件的 ProcessSpiderOutput 收到当前响应，
+// 请求深度为当前响应深度加1
+func (r *Response) NativeRequest(req *http.Request, parser Parser, meta map[string]interface{}, opts ...RequestOption) {
+	r.engine.emit(r, NewRequest(req, parser, meta, opts...))
 }
 
-func (r *Response) Meta() map[string]interface{} {
-	return r.meta
+// Push 在当前响应中发送数据，数据经过爬虫中间件，中间件的 ProcessSpiderOutput 收到当前响应
+func (r *Response) Push(item interface{}) {
+	r.engine.emit(r, item)
 }
 
 func (r *Response) close() {
 	_ = r.Response.Body.Close()
 }
 
-func (r *Response) XPath(selector string) *goquery.Selection {
-	dom, _ := goquery.NewDocumentFromReader(strings.NewReader(""))
-	return dom.Find(selector)
+// discard 丢弃响应，关闭响应体以便复用连接并停止读写计时
+func (r *Response) discard() {
+	if r != nil && r.Valid() {
+		r.close()
+	}
 }
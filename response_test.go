@@ -0,0 +1,51 @@
+package gugo
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestResponseBodyReread(t *testing.T) {
+	body := &closeBody{Reader: strings.NewReader(`{"name":"gugo"}`)}
+	res := &Response{Response: &http.Response{Body: body}}
+	for i := 0; i < 3; i++ {
+		if got := string(res.Body()); got != `{"name":"gugo"}` {
+			t.Fatalf("Body call %d = %q", i, got)
+		}
+	}
+	if !body.closed {
+		t.Error("network body is not closed after it is buffered")
+	}
+	var v struct{ Name string }
+	if err := res.JSON(&v); err != nil || v.Name != "gugo" {
+		t.Errorf("JSON = %v, %v", v, err)
+	}
+	// 缓存后 Reader 和 http.Response.Body 都从头读取缓存
+	for _, r := range []io.Reader{res.Reader(), res.Response.Body} {
+		if b, err := io.ReadAll(r); err != nil || string(b) != `{"name":"gugo"}` {
+			t.Errorf("read after Body = %q, %v", b, err)
+		}
+	}
+	if res.BodyErr() != nil {
+		t.Errorf("BodyErr = %v", res.BodyErr())
+	}
+}
+
+func TestResponseBodySharedWithMiddleware(t *testing.T) {
+	var calls []string
+	var seen []byte
+	d := newDownloader()
+	d.SetDownloaderMiddleware(funcMiddleware{name: "m", calls: &calls, request: func(req *Request) (*Request, *Response, error) {
+		return nil, &Response{Response: &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("shared"))}}, nil
+	}, response: func(req *Request, res *Response) (*Request, *Response, error) {
+		seen = res.Body()
+		return nil, res, nil
+	}})
+	r, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	_, res := downloadOnce(d, NewRequest(r, nil, nil))
+	if string(seen) != "shared" || string(res.Body()) != "shared" || &seen[0] != &res.Body()[0] {
+		t.Errorf("middleware saw %q, parser got %q, want the same buffered body", seen, res.Body())
+	}
+}
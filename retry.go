@@ -0,0 +1,74 @@
+package gugo
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RetryBackoff    = time.Second // 默认重试退避基础时间
+	MaxRetryBackoff = time.Minute // 默认重试退避最大时间
+	RetryJitter     = 0.5         // 默认重试退避随机抖动比例
+)
+
+// backoff 第n次重试前等待的时间：指数退避并减去随机抖动，响应头指定了 Retry-After 时以其为准，不超过最大退避时间
+func (d *downloader) backoff(n uint32, err error) time.Duration {
+	var re *RequestError
+	if errors.As(err, &re) && re.RetryAfter > 0 {
+		if re.RetryAfter > d.maxRetryBackoff {
+			return d.maxRetryBackoff
+		}
+		return re.RetryAfter
+	}
+	delay := d.maxRetryBackoff
+	if n > 0 && n <= 32 {
+		if b := d.retryBackoff << (n - 1); b > 0 && b < delay {
+			delay = b
+		}
+	}
+	return delay - time.Duration(d.retryJitter*rand.Float64()*float64(delay))
+}
+
+// retryable 错误是否可以重试：响应体过大时重试也无济于事；
+// Retry-After 超过最大退避时间时不重试，否则请求长时间等待重试，引擎无法空闲退出
+func (d *downloader) retryable(err error) bool {
+	var re *RequestError
+	if errors.As(err, &re) && re.RetryAfter > d.maxRetryBackoff {
+		return false
+	}
+	return !errors.Is(err, ErrTooLarge)
+}
+
+// parseRetryAfter 解析响应头 Retry-After，支持秒数和HTTP日期两种格式
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		return 0
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// SetRetryBackoff 设置重试退避的基础时间和最大时间，第n次重试前等待 base*2^(n-1)，不超过max
+func (d *downloader) SetRetryBackoff(base, max time.Duration) {
+	d.retryBackoff, d.maxRetryBackoff = base, max
+}
+
+// SetRetryJitter 设置重试退避的随机抖动比例，取值0~1，等待时间会随机减少最多该比例
+func (d *downloader) SetRetryJitter(jitter float64) {
+	d.retryJitter = jitter
+}
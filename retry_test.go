@@ -0,0 +1,92 @@
+package gugo
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"", 0, 0},
+		{"120", 120 * time.Second, 120 * time.Second},
+		{" 3 ", 3 * time.Second, 3 * time.Second},
+		{"0", 0, 0},
+		{"-5", 0, 0},
+		{"soon", 0, 0},
+		{time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat), 28 * time.Second, 30 * time.Second},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value); got < tt.min || got > tt.max {
+			t.Errorf("parseRetryAfter(%q) = %s, want between %s and %s", tt.value, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := newDownloader()
+	d.SetRetryBackoff(time.Second, 10*time.Second)
+	d.SetRetryJitter(0)
+	err := errors.New("network error")
+	tests := []struct {
+		n    uint32
+		want time.Duration
+	}{
+		{0, 10 * time.Second},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{64, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := d.backoff(tt.n, err); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+	retryAfter := &RequestError{Kind: ErrRetryHTTPCode, RetryAfter: 3 * time.Second}
+	if got := d.backoff(1, retryAfter); got != 3*time.Second {
+		t.Errorf("backoff with Retry-After = %s, want 3s", got)
+	}
+	retryAfter.RetryAfter = 24 * time.Hour
+	if got := d.backoff(1, retryAfter); got != 10*time.Second {
+		t.Errorf("backoff with a long Retry-After = %s, want the 10s cap", got)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	d := newDownloader()
+	d.SetRetryBackoff(time.Second, time.Minute)
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("network error"), true},
+		{&RequestError{Kind: ErrRetryHTTPCode, RetryAfter: time.Minute}, true},
+		{&RequestError{Kind: ErrRetryHTTPCode, RetryAfter: 24 * time.Hour}, false},
+		{&RequestError{Kind: ErrTooLarge}, false},
+	}
+	for _, tt := range tests {
+		if got := d.retryable(tt.err); got != tt.want {
+			t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	d := newDownloader()
+	d.SetRetryBackoff(time.Second, time.Minute)
+	d.SetRetryJitter(0.5)
+	for i := 0; i < 100; i++ {
+		if got := d.backoff(2, nil); got < time.Second || got > 2*time.Second {
+			t.Fatalf("backoff(2) = %s, want between 1s and 2s", got)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package gugo
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errRobotsTxt 请求被 robots.txt 禁止
+var errRobotsTxt = errors.New("disallowed by robots.txt")
+
+// disallowAll robots.txt 无法访问时使用的规则：全部禁止
+var disallowAll = parseRobots(strings.NewReader("User-agent: *\nDisallow: /\n"))
+
+// robotsRule 一条 Allow/Disallow 规则
+type robotsRule struct {
+	allow   bool
+	length  int            // 规则长度，越长越具体
+	pattern *regexp.Regexp // 支持通配符*和结尾符$
+}
+
+// robotsGroup 一组 User-agent 对应的规则
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// robotsRules 某个域名的 robots.txt，nil表示全部允许
+type robotsRules struct {
+	groups []*robotsGroup
+}
+
+// parseRobots 解析 robots.txt
+func parseRobots(r io.Reader) *robotsRules {
+	rules := &robotsRules{}
+	var group *robotsGroup
+	inAgents := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				group = &robotsGroup{}
+				rules.groups = append(rules.groups, group)
+			}
+			group.agents = append(group.agents, strings.ToLower(value))
+			inAgents = true
+			continue
+		case "allow", "disallow":
+			if group != nil && value != "" {
+				group.rules = append(group.rules, robotsRule{
+					allow:   key == "allow",
+					length:  len(value),
+					pattern: robotsPattern(value),
+				})
+			}
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && group != nil && seconds > 0 {
+				group.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+		inAgents = false
+	}
+	return rules
+}
+
+// robotsPattern 规则路径转换为正则表达式
+func robotsPattern(path string) *regexp.Regexp {
+	anchored := strings.HasSuffix(path, "$")
+	path = strings.TrimSuffix(path, "$")
+	parts := strings.Split(path, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// group 选出适用于 userAgent 的规则组：名字包含在 userAgent 中且最长的组，其次是*组
+func (rs *robotsRules) group(userAgent string) *robotsGroup {
+	if rs == nil {
+		return nil
+	}
+	userAgent = strings.ToLower(userAgent)
+	var matched, wildcard *robotsGroup
+	length := 0
+	for _, g := range rs.groups {
+		for _, agent := range g.agents {
+			switch {
+			case agent == "*":
+				if wildcard == nil {
+					wildcard = g
+				}
+			case strings.Contains(userAgent, agent) && len(agent) > length:
+				matched, length = g, len(agent)
+			}
+		}
+	}
+	if matched != nil {
+		return matched
+	}
+	return wildcard
+}
+
+// allowed 路径(含查询参数)是否允许访问：最长匹配的规则生效，长度相同时 Allow 优先
+func (rs *robotsRules) allowed(userAgent, path string) bool {
+	g := rs.group(userAgent)
+	if g == nil || path == "/robots.txt" {
+		return true
+	}
+	allow, length := true, -1
+	for _, rule := range g.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > length || rule.length == length && rule.allow {
+			allow, length = rule.allow, rule.length
+		}
+	}
+	return allow
+}
+
+// robotsEntry 域名的 robots.txt 缓存，done 关闭后 rules 可用
+type robotsEntry struct {
+	done  chan struct{}
+	rules *robotsRules
+}
+
+// robotsTxt robots.txt 下载器中间件：每个域名的 robots.txt 只下载一次，
+// 被禁止的请求记入调度器的拦截数量，Crawl-delay 作为域名的最小下载间隔。
+// robots.txt 在请求入队时就开始在后台下载，下载完成前该域名的请求不会出队，不占用全局并发许可。
+// 按 RFC 9309，robots.txt 不存在(4xx)时全部允许，服务器错误(5xx)或网络错误时按重试设置退避重试，仍然失败时全部禁止
+type robotsTxt struct {
+	BaseDownloaderMiddleware
+	e         *engine
+	userAgent string
+	stop      <-chan struct{} // 引擎停止的信号，停止后不再重试下载 robots.txt
+	mu        sync.Mutex
+	entries   map[string]*robotsEntry
+}
+
+func (rt *robotsTxt) ProcessRequest(req *Request) (*Request, *Response, error) {
+	rules := rt.rules(req)
+	if rules.allowed(rt.userAgent, req.Request.URL.RequestURI()) {
+		return req, nil, nil
+	}
+	log.Printf("%s is disallowed by robots.txt\n", req.URL())
+	rt.e.scheduler.DecrAcceptedCount()
+	rt.e.scheduler.IncrInterceptCount()
+	return nil, nil, &RequestError{Kind: ErrFiltered, URL: req.URL(), Err: errRobotsTxt}
+}
+
+// entry 获取请求所在域名的 robots.txt 缓存，不存在时创建，第二个返回值表示是否新创建
+func (rt *robotsTxt) entry(req *Request) (*robotsEntry, string, bool) {
+	key := req.Request.URL.Scheme + "://" + req.Request.URL.Host
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	entry, ok := rt.entries[key]
+	if !ok {
+		entry = &robotsEntry{done: make(chan struct{})}
+		rt.entries[key] = entry
+	}
+	return entry, key, !ok
+}
+
+// prefetch 请求入队前调用，域名的 robots.txt 尚未下载时在后台下载，下载完成前该域名的下载槽不能被占用
+func (rt *robotsTxt) prefetch(req *Request) {
+	entry, key, created := rt.entry(req)
+	if !created {
+		return
+	}
+	sl := rt.e.slot(req.Host())
+	sl.gate()
+	go func() {
+		defer sl.ungate()
+		defer close(entry.done)
+		entry.rules = rt.load(key, sl)
+	}()
+}
+
+// rules 获取请求所在域名的 robots.txt，通常已经由 prefetch 下载完成，
+// 在开启 robots.txt 协议之前入队的请求第一次访问时下载，同一域名的其他请求等待下载完成
+func (rt *robotsTxt) rules(req *Request) *robotsRules {
+	entry, key, created := rt.entry(req)
+	if !created {
+		<-entry.done
+		return entry.rules
+	}
+	defer close(entry.done)
+	entry.rules = rt.load(key, rt.e.slot(req.Host()))
+	return entry.rules
+}
+
+// load 下载 robots.txt，无法访问时按重试设置退避重试，超过最大重试次数后全部禁止
+func (rt *robotsTxt) load(base string, sl *slot) *robotsRules {
+	for n := uint32(1); ; n++ {
+		rules, err := rt.fetch(base, sl)
+		if err == nil {
+			return rules
+		}
+		if n > rt.e.maxRetry {
+			log.Printf("fetch %s/robots.txt failed, all disallowed: %v\n", base, err)
+			return disallowAll
+		}
+		wait := rt.e.backoff(n, err)
+		log.Printf("fetch %s/robots.txt failed, retry %d after %s: %v\n", base, n, wait, err)
+		select {
+		case <-time.After(wait):
+		case <-rt.stop:
+			return disallowAll
+		}
+	}
+}
+
+// fetch 通过下载器下载 robots.txt，不存在时全部允许，服务器错误或网络错误时返回错误
+func (rt *robotsTxt) fetch(base string, sl *slot) (*robotsRules, error) {
+	r, err := http.NewRequest(http.MethodGet, base+"/robots.txt", nil)
+	if err != nil {
+		return nil, nil
+	}
+	r.Header.Set("User-Agent", rt.userAgent)
+	res, err := rt.e.do(NewRequest(r, nil, nil), sl)
+	if err != nil {
+		return nil, err
+	}
+	defer res.close()
+	if res.StatusCode >= 500 {
+		return nil, &RequestError{Kind: ErrRetryHTTPCode, URL: base + "/robots.txt", StatusCode: res.StatusCode}
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, nil
+	}
+	rules := parseRobots(res.Response.Body)
+	if g := rules.group(rt.userAgent); g != nil && g.crawlDelay > 0 {
+		sl.setCrawlDelay(g.crawlDelay)
+	}
+	return rules, nil
+}
+
+// SetRobotsTxt 开启 robots.txt 协议，按 userAgent 匹配规则，需要在发起请求之前设置，
+// robots.txt 中间件始终位于下载器中间件链的最前面。
+// 同时把 userAgent 设为默认请求头的 User-Agent，使网站看到的身份与遵守的规则一致；
+// 请求自身或请求头模板中的 User-Agent 优先于默认请求头，此时需要自行保持一致
+func (e *engine) SetRobotsTxt(userAgent string) {
+	rt := &robotsTxt{e: e, userAgent: userAgent, stop: ctx.Done(), entries: make(map[string]*robotsEntry)}
+	e.downloader.middlewares = append([]DownloaderMiddleware{rt}, e.downloader.middlewares...)
+	e.scheduler.onAccept = rt.prefetch
+	e.SetDefaultHeader("User-Agent", userAgent)
+}
@@ -0,0 +1,150 @@
+package gugo
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const robotsSample = `
+# comment
+User-agent: gugo
+User-agent: other
+Disallow: /private
+Allow: /private/public
+Disallow: /*?sort=
+Disallow: /*.pdf$
+Crawl-delay: 1.5
+
+User-agent: *
+Disallow: /
+`
+
+func TestParseRobotsGroup(t *testing.T) {
+	rules := parseRobots(strings.NewReader(robotsSample))
+	if len(rules.groups) != 2 {
+		t.Fatalf("groups = %d, want 2", len(rules.groups))
+	}
+	tests := []struct {
+		userAgent  string
+		crawlDelay time.Duration
+		agents     int
+	}{
+		{"Mozilla/5.0 (compatible; gugo/1.0)", 1500 * time.Millisecond, 2},
+		{"OTHER", 1500 * time.Millisecond, 2},
+		{"somebot", 0, 1},
+	}
+	for _, tt := range tests {
+		g := rules.group(tt.userAgent)
+		if g == nil {
+			t.Fatalf("group(%q) = nil", tt.userAgent)
+		}
+		if g.crawlDelay != tt.crawlDelay || len(g.agents) != tt.agents {
+			t.Errorf("group(%q) = {crawlDelay: %s, agents: %d}, want {%s, %d}",
+				tt.userAgent, g.crawlDelay, len(g.agents), tt.crawlDelay, tt.agents)
+		}
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	rules := parseRobots(strings.NewReader(robotsSample))
+	tests := []struct {
+		userAgent string
+		path      string
+		want      bool
+	}{
+		{"gugo", "/", true},
+		{"gugo", "/private", false},
+		{"gugo", "/private/secret", false},
+		{"gugo", "/private/public/page", true},
+		{"gugo", "/list?sort=1", false},
+		{"gugo", "/list?page=1", true},
+		{"gugo", "/doc/a.pdf", false},
+		{"gugo", "/doc/a.pdf?download=1", true},
+		{"somebot", "/", false},
+		{"somebot", "/robots.txt", true},
+	}
+	for _, tt := range tests {
+		if got := rules.allowed(tt.userAgent, tt.path); got != tt.want {
+			t.Errorf("allowed(%q, %q) = %v, want %v", tt.userAgent, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRobotsAllowedTie(t *testing.T) {
+	rules := parseRobots(strings.NewReader("User-agent: *\nDisallow: /page\nAllow: /page\n"))
+	if !rules.allowed("gugo", "/page") {
+		t.Error("allowed = false, want Allow to win a tie of the same length")
+	}
+	var none *robotsRules
+	if !none.allowed("gugo", "/anything") {
+		t.Error("nil rules should allow everything")
+	}
+}
+
+func TestRobotsUnreachable(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.Host]++
+		n := hits[r.Host]
+		mu.Unlock()
+		switch {
+		case strings.HasPrefix(r.Host, "down."):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case strings.HasPrefix(r.Host, "flaky.") && n == 1:
+			w.WriteHeader(http.StatusNotImplemented)
+		case strings.HasPrefix(r.Host, "missing."):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+		}
+	}))
+	defer srv.Close()
+	e := newEngine()
+	e.SetMaxRetry(2)
+	e.SetRetryBackoff(time.Millisecond, time.Millisecond)
+	e.SetRobotsTxt("gugo")
+	rt := e.downloader.middlewares[0].(*robotsTxt)
+	rt.stop = nil
+	if got := e.downloader.defaultHeader.Get("User-Agent"); got != "gugo" {
+		t.Errorf("default User-Agent = %q, want the robots.txt user agent", got)
+	}
+	// 用 Host 区分不同的网站，都连接到同一个测试服务器
+	addr := strings.TrimPrefix(srv.URL, "http://")
+	e.SetClient(&http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}})
+	tests := []struct {
+		host    string
+		hits    int
+		public  bool
+		private bool
+	}{
+		{"down.test", 3, false, false},
+		{"flaky.test", 2, true, false},
+		{"missing.test", 1, true, true},
+	}
+	for _, tt := range tests {
+		rules := rt.load("http://"+tt.host, e.slot(tt.host))
+		if got := rules.allowed("gugo", "/page"); got != tt.public {
+			t.Errorf("%s: allowed(/page) = %v, want %v", tt.host, got, tt.public)
+		}
+		if got := rules.allowed("gugo", "/private"); got != tt.private {
+			t.Errorf("%s: allowed(/private) = %v, want %v", tt.host, got, tt.private)
+		}
+		mu.Lock()
+		if hits[tt.host] != tt.hits {
+			t.Errorf("%s: hits = %d, want %d", tt.host, hits[tt.host], tt.hits)
+		}
+		mu.Unlock()
+	}
+}
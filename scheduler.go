@@ -10,17 +10,28 @@ import (
 const (
 	FalsePositive     = 0.01    // 默认过滤错误容忍率
 	EstimateRequest   = 1000000 // 默认估计100万请求
-	RequestBufferCap  = 1 << 12 // 默认请求队列容量
 	ConcurrentRequest = 1 << 10 // 默认并发请求数
 )
 
+// RequestBufferCap 默认请求队列容量
+//
+// Deprecated: 请求队列改为按域名划分的优先级队列，不再限制容量
+const RequestBufferCap = 1 << 12
+
 type scheduler struct {
 	smu               sync.Mutex
-	filter            *bloom.BloomFilter  // 布隆过滤器
-	domain            map[string]struct{} // 可用域名
-	reqBuf            chan *request       // 请求队列
-	concurrentRequest chan struct{}       // 请求并发控制
-	duration          time.Duration       // 下载延时
+	filter            *bloom.BloomFilter          // 布隆过滤器
+	domain            map[string]struct{}         // 可用域名
+	frontier          *frontier                   // 请求队列
+	concurrentRequest chan struct{}               // 请求并发控制
+	pending           map[*Request]*RequestRecord // 尚未处理完毕的请求及其被接受时的序列化格式
+	slots             map[string]*slot            // 域名下载槽
+	delay             time.Duration               // 默认域名令牌产生间隔
+	burst             uint32                      // 默认域名令牌桶容量
+	concurrentDomain  uint32                      // 默认域名最大并发数
+	depthLimit        uint32                      // 最大深度，0表示不限制
+	onAccept          func(*Request)              // 请求被接受后、入队前的处理，如预先下载 robots.txt
+	isCacheHit        func(*Request) bool         // 请求是否命中新鲜的HTTP缓存
 	*module
 }
 
@@ -28,40 +39,92 @@ func newScheduler() *scheduler {
 	return &scheduler{
 		filter:            bloom.NewWithEstimates(EstimateRequest, FalsePositive),
 		domain:            make(map[string]struct{}),
-		reqBuf:            make(chan *request, RequestBufferCap),
+		frontier:          newFrontier(),
 		concurrentRequest: make(chan struct{}, ConcurrentRequest),
+		pending:           make(map[*Request]*RequestRecord),
+		slots:             make(map[string]*slot),
 		module:            &module{},
 	}
 }
 
-// ask 统计项：
+// ask 被过滤的请求会调用请求的错误回调，统计项：
 // 1、调度器正在处理的数量
 // 2、客户端发起请求的数量
 // 3、请求被拦截过滤的数量
 // 4、请求被接受下载的数量
-func (s *scheduler) ask(r *request) {
+func (s *scheduler) ask(r *Request) {
 	s.IncrHandlingNumber()
 	defer s.DecrHandlingNumber()
 	s.IncrCalledCount()
 	if !s.isAcceptedRequest(r) {
 		s.IncrInterceptCount()
+		err := &RequestError{Kind: ErrFiltered}
+		if r.Request != nil && r.Request.URL != nil {
+			err.URL = r.URL()
+		}
+		r.fail(err)
 		return
 	}
 	s.IncrAcceptedCount()
-	time.Sleep(s.duration)
-	go func() { s.reqBuf <- r }()
+	s.enqueue(r)
+}
+
+// enqueue 已接受的请求放入请求队列
+func (s *scheduler) enqueue(r *Request) {
+	s.track(r)
+	if s.onAccept != nil {
+		s.onAccept(r)
+	}
+	r.cacheHit = s.isCacheHit != nil && s.isCacheHit(r)
+	s.frontier.push(r)
+}
+
+// track 记录尚未处理完毕的请求，请求从被接受开始，直到响应解析完成、最终下载失败或被丢弃为止。
+// 此时请求尚未分发下载，同时保存序列化格式，断点不再读取正在下载的请求
+func (s *scheduler) track(r *Request) {
+	record := r.snapshot()
+	s.smu.Lock()
+	defer s.smu.Unlock()
+	s.pending[r] = record
+}
+
+// untrack 请求处理完毕
+func (s *scheduler) untrack(r *Request) {
+	s.smu.Lock()
+	defer s.smu.Unlock()
+	delete(s.pending, r)
+}
+
+// retrack 请求被替换为新的请求
+func (s *scheduler) retrack(old, new *Request) {
+	if old != new {
+		s.untrack(old)
+		s.track(new)
+	}
+}
+
+// pendingRecords 尚未处理完毕的请求被接受时的序列化格式
+func (s *scheduler) pendingRecords() []*RequestRecord {
+	s.smu.Lock()
+	defer s.smu.Unlock()
+	records := make([]*RequestRecord, 0, len(s.pending))
+	for _, record := range s.pending {
+		records = append(records, record)
+	}
+	return records
 }
 
 // isAcceptedRequest 判断请求是否可访问
-func (s *scheduler) isAcceptedRequest(r *request) bool {
+func (s *scheduler) isAcceptedRequest(r *Request) bool {
 	return r.Valid() &&
+		s.isAcceptedDepth(r) &&
 		s.isAcceptedDomain(r) &&
 		s.isAcceptedSchema(r) &&
 		s.isUniqueRequest(r)
 }
 
 // isUniqueRequest 判断请求是否重复
-func (s *scheduler) isUniqueRequest(r *request) bool {
+func (s *scheduler) isUniqueRequest(r *Request) bool {
 	s.smu.Lock()
 	defer s.smu.Unlock()
 	if !s.filter.Test(r.FingerPrint()) {
@@ -73,7 +136,7 @@ func (s *scheduler) isUniqueRequest(r *request) bool {
 }
 
 // isAcceptedSchema 判断请求协议是否可访问
-func (s *scheduler) isAcceptedSchema(r *request) bool {
+func (s *scheduler) isAcceptedSchema(r *Request) bool {
 	if r.Schema() == "http" || r.Schema() == "https" {
 		return true
 	}
@@ -81,8 +144,17 @@ func (s *scheduler) isAcceptedSchema(r *request) bool {
 	return false
 }
 
+// isAcceptedDepth 判断请求深度是否超过最大深度
+func (s *scheduler) isAcceptedDepth(r *Request) bool {
+	if s.depthLimit == 0 || r.depth <= int(s.depthLimit) {
+		return true
+	}
+	log.Printf("%s depth %d exceeds depth limit %d\n", r.URL(), r.depth, s.depthLimit)
+	return false
+}
+
 // isAcceptedDomain 判断请求域名是否可访问
-func (s *scheduler) isAcceptedDomain(r *request) bool {
+func (s *scheduler) isAcceptedDomain(r *Request) bool {
 	if len(s.domain) == 0 {
 		return true
 	}
@@ -93,6 +165,18 @@ func (s *scheduler) isAcceptedDomain(r *request) bool {
 	return false
 }
 
+// slot 获取域名下载槽，不存在时按默认配置创建
+func (s *scheduler) slot(host string) *slot {
+	s.smu.Lock()
+	defer s.smu.Unlock()
+	sl, ok := s.slots[host]
+	if !ok {
+		sl = newSlot(host, s.delay, s.burst, s.concurrentDomain, s.frontier.signal)
+		s.slots[host] = sl
+	}
+	return sl
+}
+
 // SetDomain 设置可访问的域名
 func (s *scheduler) SetDomain(domain ...string) {
 	for _, v := range domain {
@@ -103,11 +187,47 @@ func (s *scheduler) SetDomain(domain ...string) {
 }
 
 // SetRequestBufCap 设置请求队列容量
-func (s *scheduler) SetRequestBufCap(n uint32) {
-	s.reqBuf = make(chan *request, n)
+//
+// Deprecated: 请求队列改为按域名划分的优先级队列，不再限制容量
+func (s *scheduler) SetRequestBufCap(n uint32) {}
+
+// SetDepthLimit 设置最大深度，超过最大深度的请求会被拦截，0表示不限制
+func (s *scheduler) SetDepthLimit(n uint32) {
+	s.depthLimit = n
+}
+
+// SetDepthPriority 设置按深度调整优先级：出队时请求优先级减去 深度*n，请求自身的优先级不变，
+// n为正数时浅层请求优先(趋向广度优先)，n为负数时深层请求优先(趋向深度优先)，需要在发起请求之前设置
+func (s *scheduler) SetDepthPriority(n int) {
+	s.frontier.depthPriority = n
+}
+
+// SetCrawlOrder 设置请求的出队顺序，需要在发起请求之前设置
+func (s *scheduler) SetCrawlOrder(order Order) {
+	s.frontier.order = order
 }
 
 // SetConcurrentRequest 设置请求处理的并发量
 func (s *scheduler) SetConcurrentRequest(n uint32) {
 	s.concurrentRequest = make(chan struct{}, n)
 }
+
+// SetRateLimit 设置每个域名默认的限速：每秒请求数和令牌桶容量，rate为0表示不限速
+func (s *scheduler) SetRateLimit(rate float64, burst uint32) {
+	s.delay, s.burst = rateToDelay(rate), burst
+}
+
+// SetDomainRateLimit 设置指定域名的限速：每秒请求数和令牌桶容量，rate为0表示不限速
+func (s *scheduler) SetDomainRateLimit(domain string, rate float64, burst uint32) {
+	s.slot(domain).setRate(rateToDelay(rate), burst)
+}
+
+// SetConcurrentDomain 设置每个域名默认的最大并发数，0表示不限制
+func (s *scheduler) SetConcurrentDomain(n uint32) {
+	s.concurrentDomain = n
+}
+
+// SetDomainConcurrent 设置指定域名的最大并发数，0表示不限制
+func (s *scheduler) SetDomainConcurrent(domain string, n uint32) {
+	s.slot(domain).setLimit(n)
+}
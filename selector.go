@@ -0,0 +1,305 @@
+package gugo
+
+import (
+	"bytes"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Selector 选择器，选中的HTML节点，或选中的属性值、文本，可以继续用 CSS、XPath、Re 链式选择
+type Selector struct {
+	node *html.Node
+	text string // node为nil时选中的字符串
+}
+
+// SelectorList 选择器列表
+type SelectorList []*Selector
+
+var (
+	xpathCache sync.Map // 编译过的XPath表达式
+	reCache    sync.Map // 编译过的正则表达式
+)
+
+// compileXPath 编译XPath表达式，结果会被缓存
+func compileXPath(expr string) (*xpath.Expr, error) {
+	if e, ok := xpathCache.Load(expr); ok {
+		return e.(*xpath.Expr), nil
+	}
+	e, err := xpath.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	xpathCache.Store(expr, e)
+	return e, nil
+}
+
+// root 解析响应体得到的HTML文档，只解析一次
+func (r *Response) root() *html.Node {
+	if r.doc == nil {
+		doc, err := html.Parse(strings.NewReader(r.Text()))
+		if err != nil {
+			log.Printf("%s parse html failed: %v\n", r.URL(), err)
+			doc = &html.Node{Type: html.DocumentNode}
+		}
+		r.doc = doc
+	}
+	return r.doc
+}
+
+// Document 响应体对应的 goquery 文档，与 CSS、XPath 共享同一棵解析树
+func (r *Response) Document() *goquery.Document {
+	if r.document == nil {
+		r.document = goquery.NewDocumentFromNode(r.root())
+	}
+	return r.document
+}
+
+// Selector 整个HTML文档的选择器
+func (r *Response) Selector() *Selector {
+	return &Selector{node: r.root()}
+}
+
+// CSS 在整个HTML文档中按CSS选择器选择，见 Selector.CSS
+func (r *Response) CSS(query string) SelectorList {
+	return r.Selector().CSS(query)
+}
+
+// XPath 在整个HTML文档中按XPath表达式选择，见 Selector.XPath
+func (r *Response) XPath(expr string) SelectorList {
+	return r.Selector().XPath(expr)
+}
+
+// Re 在响应体文本中按正则表达式提取，见 Selector.Re
+func (r *Response) Re(pattern string) []string {
+	return (&Selector{text: r.Text()}).Re(pattern)
+}
+
+// CSS 在选中的节点下按CSS选择器选择，选择器不合法时返回空列表。
+// 支持伪元素：::text 选择节点的直接文本，::attr(name) 选择节点的属性值，
+// 伪元素前的选择器为空时作用于节点自身，如 ::attr(href)
+func (s *Selector) CSS(query string) SelectorList {
+	if s.node == nil {
+		return SelectorList{}
+	}
+	query, pseudo := splitPseudo(query)
+	nodes := []*html.Node{s.node}
+	if query != "" {
+		nodes = goquery.NewDocumentFromNode(s.node).Find(query).Nodes
+	}
+	switch {
+	case pseudo == "text":
+		list := SelectorList{}
+		for _, n := range nodes {
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.TextNode {
+					list = append(list, &Selector{text: c.Data})
+				}
+			}
+		}
+		return list
+	case strings.HasPrefix(pseudo, "attr(") && strings.HasSuffix(pseudo, ")"):
+		name := strings.TrimSpace(pseudo[len("attr(") : len(pseudo)-1])
+		list := SelectorList{}
+		for _, n := range nodes {
+			for _, a := range n.Attr {
+				if a.Key == name {
+					list = append(list, &Selector{text: a.Val})
+				}
+			}
+		}
+		return list
+	case pseudo != "":
+		log.Printf("unsupported pseudo element ::%s\n", pseudo)
+		return SelectorList{}
+	}
+	return newSelectorList(nodes)
+}
+
+// XPath 在选中的节点下按XPath表达式选择，可以选择属性(如 //a/@href)和文本(如 //p/text())，
+// 表达式不合法时返回空列表
+func (s *Selector) XPath(expr string) SelectorList {
+	if s.node == nil {
+		return SelectorList{}
+	}
+	return selectXPath(s.node, expr)
+}
+
+// Re 在选中的内容中按正则表达式提取：没有分组时返回整个匹配，有分组时返回所有分组，表达式不合法时返回nil
+func (s *Selector) Re(pattern string) []string {
+	re, err := compileRe(pattern)
+	if err != nil {
+		log.Printf("invalid regexp %s: %v\n", pattern, err)
+		return nil
+	}
+	var all []string
+	for _, m := range re.FindAllStringSubmatch(s.Get(), -1) {
+		if len(m) == 1 {
+			all = append(all, m[0])
+		} else {
+			all = append(all, m[1:]...)
+		}
+	}
+	return all
+}
+
+// Extract 选中的内容，同 Get
+func (s *Selector) Extract() string {
+	return s.Get()
+}
+
+// splitPseudo 拆分CSS选择器末尾的伪元素
+func splitPseudo(query string) (string, string) {
+	i := strings.LastIndex(query, "::")
+	if i < 0 {
+		return strings.TrimSpace(query), ""
+	}
+	return strings.TrimSpace(query[:i]), strings.TrimSpace(query[i+2:])
+}
+
+// compileRe 编译正则表达式，结果会被缓存
+func compileRe(pattern string) (*regexp.Regexp, error) {
+	if re, ok := reCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	reCache.Store(pattern, re)
+	return re, nil
+}
+
+func newSelectorList(nodes []*html.Node) SelectorList {
+	list := make(SelectorList, 0, len(nodes))
+	for _, n := range nodes {
+		list = append(list, &Selector{node: n})
+	}
+	return list
+}
+
+// selectXPath 在节点下按XPath表达式选择
+func selectXPath(top *html.Node, expr string) SelectorList {
+	e, err := compileXPath(expr)
+	if err != nil {
+		log.Printf("invalid xpath %s: %v\n", expr, err)
+		return SelectorList{}
+	}
+	list := SelectorList{}
+	t := e.Select(htmlquery.CreateXPathNavigator(top))
+	for t.MoveNext() {
+		nav := t.Current().(*htmlquery.NodeNavigator)
+		if nav.NodeType() == xpath.AttributeNode {
+			list = append(list, &Selector{text: nav.Value()})
+			continue
+		}
+		list = append(list, &Selector{node: nav.Current()})
+	}
+	return list
+}
+
+// Get 选中的内容：元素节点为HTML，文本节点为文本，属性为属性值
+func (s *Selector) Get() string {
+	switch {
+	case s.node == nil:
+		return s.text
+	case s.node.Type == html.TextNode:
+		return s.node.Data
+	}
+	var buf bytes.Buffer
+	_ = html.Render(&buf, s.node)
+	return buf.String()
+}
+
+// Attr 元素节点的属性值，不存在时返回空字符串
+func (s *Selector) Attr(name string) string {
+	if s.node == nil {
+		return ""
+	}
+	for _, a := range s.node.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// CSS 在每个选择器下按CSS选择器选择，结果合并为一个列表
+func (l SelectorList) CSS(query string) SelectorList {
+	list := SelectorList{}
+	for _, s := range l {
+		list = append(list, s.CSS(query)...)
+	}
+	return list
+}
+
+// XPath 在每个选择器下按XPath表达式选择，结果合并为一个列表
+func (l SelectorList) XPath(expr string) SelectorList {
+	list := SelectorList{}
+	for _, s := range l {
+		list = append(list, s.XPath(expr)...)
+	}
+	return list
+}
+
+// Re 在每个选择器中按正则表达式提取，结果合并为一个列表
+func (l SelectorList) Re(pattern string) []string {
+	var all []string
+	for _, s := range l {
+		all = append(all, s.Re(pattern)...)
+	}
+	return all
+}
+
+// ReFirst 第一个正则表达式提取的结果，没有时返回def
+func (l SelectorList) ReFirst(pattern string, def string) string {
+	for _, s := range l {
+		if all := s.Re(pattern); len(all) > 0 {
+			return all[0]
+		}
+	}
+	return def
+}
+
+// Extract 所有选中的内容，同 GetAll
+func (l SelectorList) Extract() []string {
+	return l.GetAll()
+}
+
+// ExtractFirst 第一个选中的内容，列表为空时返回def
+func (l SelectorList) ExtractFirst(def string) string {
+	if len(l) == 0 {
+		return def
+	}
+	return l[0].Get()
+}
+
+// Get 第一个选中的内容，列表为空时返回空字符串
+func (l SelectorList) Get() string {
+	if len(l) == 0 {
+		return ""
+	}
+	return l[0].Get()
+}
+
+// GetAll 所有选中的内容
+func (l SelectorList) GetAll() []string {
+	all := make([]string, 0, len(l))
+	for _, s := range l {
+		all = append(all, s.Get())
+	}
+	return all
+}
+
+// Attr 第一个元素节点的属性值
+func (l SelectorList) Attr(name string) string {
+	if len(l) == 0 {
+		return ""
+	}
+	return l[0].Attr(name)
+}
@@ -0,0 +1,104 @@
+package gugo
+
+import (
+	"golang.org/x/net/html"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const selectorSample = `<html><body>
+<div class="item"><a href="/x" title="X">first</a><p>one <b>bold</b> two</p></div>
+<div class="item"><a href="/y">second</a><img src="/y.png"></div>
+</body></html>`
+
+func sampleSelector(t *testing.T) *Selector {
+	doc, err := html.Parse(strings.NewReader(selectorSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Selector{node: doc}
+}
+
+func TestSplitPseudo(t *testing.T) {
+	tests := []struct {
+		query  string
+		css    string
+		pseudo string
+	}{
+		{"div.item", "div.item", ""},
+		{"a::text", "a", "text"},
+		{"::attr(href)", "", "attr(href)"},
+		{" div > a ::attr( title ) ", "div > a", "attr( title )"},
+	}
+	for _, tt := range tests {
+		css, pseudo := splitPseudo(tt.query)
+		if css != tt.css || pseudo != tt.pseudo {
+			t.Errorf("splitPseudo(%q) = %q, %q, want %q, %q", tt.query, css, pseudo, tt.css, tt.pseudo)
+		}
+	}
+}
+
+func TestSelectorCSS(t *testing.T) {
+	s := sampleSelector(t)
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"a::text", []string{"first", "second"}},
+		{"a::attr(href)", []string{"/x", "/y"}},
+		{"a::attr( title )", []string{"X"}},
+		{"p::text", []string{"one ", " two"}},
+		{"a::unknown", []string{}},
+		{"div[", []string{}},
+	}
+	for _, tt := range tests {
+		if got := s.CSS(tt.query).GetAll(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CSS(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+	if got := s.CSS("div.item").CSS("::attr(class)").GetAll(); !reflect.DeepEqual(got, []string{"item", "item"}) {
+		t.Errorf("chained CSS = %q", got)
+	}
+}
+
+func TestSelectorXPath(t *testing.T) {
+	s := sampleSelector(t)
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"//a/@href", []string{"/x", "/y"}},
+		{"//img/@src", []string{"/y.png"}},
+		{"//a/text()", []string{"first", "second"}},
+		{"//div[2]/a", []string{`<a href="/y">second</a>`}},
+		{"//a[", []string{}},
+	}
+	for _, tt := range tests {
+		if got := s.XPath(tt.expr).GetAll(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("XPath(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+	items := s.XPath("//div")
+	if got := items.XPath("./a/@href").GetAll(); !reflect.DeepEqual(got, []string{"/x", "/y"}) {
+		t.Errorf("chained XPath = %q", got)
+	}
+	// 属性选中的是字符串，不能再继续选择节点
+	if got := s.XPath("//a/@href").CSS("a"); len(got) != 0 {
+		t.Errorf("CSS on attribute = %v, want empty", got.GetAll())
+	}
+}
+
+func TestSelectorListRe(t *testing.T) {
+	s := sampleSelector(t)
+	links := s.CSS("a::attr(href)")
+	if got := links.Re(`/(\w)`); !reflect.DeepEqual(got, []string{"x", "y"}) {
+		t.Errorf("Re = %q", got)
+	}
+	if got := links.ReFirst(`/z`, "none"); got != "none" {
+		t.Errorf("ReFirst = %q, want default", got)
+	}
+	if got := s.CSS("span").ExtractFirst("empty"); got != "empty" {
+		t.Errorf("ExtractFirst = %q, want default", got)
+	}
+}
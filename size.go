@@ -0,0 +1,110 @@
+package gugo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+const (
+	MaxResponseSize  = 1 << 30  // 默认响应体最大字节数，0表示不限制
+	WarnResponseSize = 32 << 20 // 默认响应体告警字节数，0表示不告警
+)
+
+// MaxSize 请求的响应体最大字节数，优先于下载器的设置，0表示使用下载器的设置
+func MaxSize(n int64) RequestOption {
+	return func(r *Request) {
+		r.maxSize = n
+	}
+}
+
+// limitBody 限制大小的响应体，超过告警字节数时记录日志，超过最大字节数时读取出错
+type limitBody struct {
+	io.ReadCloser
+	url    string
+	max    int64
+	warn   int64
+	n      int64 // 已读取的字节数
+	warned bool
+}
+
+func (b *limitBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if b.warn > 0 && !b.warned && b.n > b.warn {
+		b.warned = true
+		log.Printf("%s response size exceeds warn size %d bytes\n", b.url, b.warn)
+	}
+	if b.max > 0 && b.n > b.max {
+		return n - int(b.n-b.max), &RequestError{
+			Kind: ErrTooLarge,
+			URL:  b.url,
+			Err:  fmt.Errorf("response size exceeds %d bytes", b.max),
+		}
+	}
+	return n, err
+}
+
+// limit 限制响应体大小，Content-Length 已经超过最大字节数时直接放弃下载
+func (d *downloader) limit(req *Request, res *http.Response) error {
+	max := d.maxResponseSize
+	if req.maxSize > 0 {
+		max = req.maxSize
+	}
+	if max > 0 && res.ContentLength > max {
+		_ = res.Body.Close()
+		return &RequestError{
+			Kind: ErrTooLarge,
+			URL:  req.URL(),
+			Err:  fmt.Errorf("content length %d exceeds %d bytes", res.ContentLength, max),
+		}
+	}
+	warned := d.warnResponseSize > 0 && res.ContentLength > d.warnResponseSize
+	if warned {
+		log.Printf("%s content length %d exceeds warn size %d bytes\n", req.URL(), res.ContentLength, d.warnResponseSize)
+	}
+	res.Body = &limitBody{ReadCloser: res.Body, url: req.URL(), max: max, warn: d.warnResponseSize, warned: warned}
+	return nil
+}
+
+// onAbort 响应离开下载器后，读取响应体时超过大小限制的处理：计为下载失败并调用请求的错误回调
+func (d *downloader) onAbort(req *Request) func(error) {
+	return func(err error) {
+		log.Println(err)
+		d.DecrCompletedCount()
+		d.IncrFailedCount()
+		req.fail(err)
+	}
+}
+
+// responseReader 读取响应体，超过大小限制时放弃下载，没有响应体时按空响应体处理
+type responseReader struct {
+	res *Response
+}
+
+func (rr responseReader) Read(p []byte) (int, error) {
+	if !rr.res.Valid() {
+		return 0, io.EOF
+	}
+	n, err := rr.res.Response.Body.Read(p)
+	if err != nil && errors.Is(err, ErrTooLarge) {
+		rr.res.close()
+		if rr.res.abort != nil {
+			rr.res.abort(err)
+			rr.res.abort = nil
+		}
+	}
+	return n, err
+}
+
+// SetMaxResponseSize 设置响应体最大字节数，超过时放弃下载并调用请求的错误回调，0表示不限制
+func (d *downloader) SetMaxResponseSize(n int64) {
+	d.maxResponseSize = n
+}
+
+// SetWarnResponseSize 设置响应体告警字节数，超过时记录日志，0表示不告警
+func (d *downloader) SetWarnResponseSize(n int64) {
+	d.warnResponseSize = n
+}
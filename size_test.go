@@ -0,0 +1,89 @@
+package gugo
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResponseSizeLimit(t *testing.T) {
+	body := strings.Repeat("x", 1000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			// 不设置 Content-Length，只能在读取时发现超过限制
+			w.(http.Flusher).Flush()
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	d := newDownloader()
+	d.SetMaxResponseSize(100)
+	var failed []error
+	newRequest := func(path string, opts ...RequestOption) *Request {
+		r, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		opts = append(opts, ErrBack(func(_ *Request, err error) { failed = append(failed, err) }))
+		return NewRequest(r, nil, nil, opts...)
+	}
+
+	// Content-Length 超过限制，不下载也不重试
+	if next, res := downloadOnce(d, newRequest("/")); next != nil || res != nil {
+		t.Fatalf("download = %v, %v, want the request to fail", next, res)
+	}
+	if len(failed) != 1 || !errors.Is(failed[0], ErrTooLarge) {
+		t.Fatalf("errBack errs = %v, want ErrTooLarge", failed)
+	}
+
+	// 解析器流式读取时超过限制，计为下载失败并调用一次错误回调
+	failed = nil
+	_, res := downloadOnce(d, newRequest("/chunked"))
+	if res == nil || d.CompletedCount() != 1 {
+		t.Fatalf("response = %v, completed = %d, want the response handed to the parser", res, d.CompletedCount())
+	}
+	n, err := io.Copy(io.Discard, res.Reader())
+	if !errors.Is(err, ErrTooLarge) || n != 100 {
+		t.Errorf("Reader = %d bytes, %v, want 100 bytes and ErrTooLarge", n, err)
+	}
+	_, _ = io.Copy(io.Discard, res.Reader())
+	if len(failed) != 1 || !errors.Is(failed[0], ErrTooLarge) {
+		t.Errorf("errBack errs = %v, want one ErrTooLarge", failed)
+	}
+	if d.CompletedCount() != 0 || d.FailedCount() != 2 {
+		t.Errorf("completed, failed = %d, %d, want 0, 2", d.CompletedCount(), d.FailedCount())
+	}
+
+	// 请求自己的限制优先于下载器的限制，SaveTo 完整保存
+	failed = nil
+	_, res = downloadOnce(d, newRequest("/chunked", MaxSize(2000)))
+	path := filepath.Join(t.TempDir(), "body")
+	if n, err := res.SaveTo(path); err != nil || n != 1000 || len(failed) != 0 {
+		t.Errorf("SaveTo = %d, %v, errBack errs = %v, want 1000 bytes", n, err, failed)
+	}
+
+	// 保存时超过限制，删除文件
+	_, res = downloadOnce(d, newRequest("/chunked"))
+	if _, err := res.SaveTo(path); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("SaveTo err = %v, want ErrTooLarge", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file is not removed after SaveTo fails: %v", err)
+	}
+
+	// 中间件读取响应体时超过限制，响应不交给解析器
+	failed = nil
+	var calls []string
+	d.SetDownloaderMiddleware(funcMiddleware{name: "m", calls: &calls, response: func(req *Request, res *Response) (*Request, *Response, error) {
+		res.Body()
+		return nil, res, nil
+	}})
+	if _, res = downloadOnce(d, newRequest("/chunked")); res != nil {
+		t.Error("response exceeding the limit in a middleware is handed to the parser")
+	}
+	if len(failed) != 1 || !errors.Is(failed[0], ErrTooLarge) {
+		t.Errorf("errBack errs = %v, want ErrTooLarge", failed)
+	}
+}
@@ -0,0 +1,160 @@
+package gugo
+
+import (
+	"sync"
+	"time"
+)
+
+// slot 域名下载槽，按域名进行令牌桶限速和并发控制
+type slot struct {
+	mu         sync.Mutex
+	host       string        // 域名
+	delay      time.Duration // 令牌产生间隔，0表示不限速
+	minDelay   time.Duration // 设置的令牌产生间隔，自动限速不会低于该值
+	burst      uint32        // 令牌桶容量
+	crawlDelay time.Duration // robots.txt 的 Crawl-delay，令牌产生间隔始终不小于该值
+	tokens     float64       // 当前令牌数
+	last       time.Time     // 上次补充令牌的时间
+	limit      uint32        // 最大并发数，0表示不限制
+	maxLimit   uint32        // 设置的最大并发数，自动限速不会超过该值，0表示不限制
+	active     uint32        // 正在下载的请求数
+	gated      uint32        // 正在下载的 robots.txt 数量，期间下载槽不能被占用
+	notify     func()        // 下载槽可能变为可用时的通知
+	breaker
+}
+
+func newSlot(host string, delay time.Duration, burst uint32, limit uint32, notify func()) *slot {
+	if burst == 0 {
+		burst = 1
+	}
+	return &slot{
+		host:     host,
+		delay:    delay,
+		minDelay: delay,
+		burst:    burst,
+		tokens:   float64(burst),
+		last:     time.Now(),
+		limit:    limit,
+		maxLimit: limit,
+		notify:   notify,
+	}
+}
+
+// ready 下载槽是否可以立即占用：0表示可以，大于0表示需要等待的时间，小于0表示需要等待并发许可或信号
+func (s *slot) ready() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.gated > 0 || s.limit > 0 && s.active >= s.limit {
+		return -1
+	}
+	if wait := s.blocked(); wait != 0 {
+		return wait
+	}
+	return s.wait()
+}
+
+// tryAcquire 尝试占用下载槽，成功时消耗一个令牌和一个并发许可
+func (s *slot) tryAcquire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.gated > 0 || s.limit > 0 && s.active >= s.limit || s.blocked() != 0 || s.wait() > 0 {
+		return false
+	}
+	s.probe()
+	s.active++
+	if delay, _ := s.interval(); delay > 0 {
+		s.tokens--
+	}
+	return true
+}
+
+// release 归还并发许可
+func (s *slot) release() {
+	s.mu.Lock()
+	s.active--
+	s.mu.Unlock()
+	s.signal()
+}
+
+// interval 实际的令牌产生间隔和令牌桶容量，Crawl-delay 生效时不允许突发，调用方需持有锁
+func (s *slot) interval() (time.Duration, uint32) {
+	if s.crawlDelay > s.delay {
+		return s.crawlDelay, 1
+	}
+	return s.delay, s.burst
+}
+
+// wait 补充令牌，返回获得一个令牌需要等待的时间
+func (s *slot) wait() time.Duration {
+	delay, burst := s.interval()
+	if delay <= 0 {
+		return 0
+	}
+	now := time.Now()
+	s.tokens += float64(now.Sub(s.last)) / float64(delay)
+	s.last = now
+	if s.tokens > float64(burst) {
+		s.tokens = float64(burst)
+	}
+	if s.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - s.tokens) * float64(delay))
+}
+
+// signal 通知下载槽可能变为可用
+func (s *slot) signal() {
+	if s.notify != nil {
+		s.notify()
+	}
+}
+
+// setRate 设置令牌产生间隔和令牌桶容量
+func (s *slot) setRate(delay time.Duration, burst uint32) {
+	s.mu.Lock()
+	if burst == 0 {
+		burst = 1
+	}
+	s.delay, s.minDelay, s.burst = delay, delay, burst
+	s.mu.Unlock()
+	s.signal()
+}
+
+// setCrawlDelay 设置 Crawl-delay，限速和自动限速都不会使下载间隔小于该值
+func (s *slot) setCrawlDelay(delay time.Duration) {
+	s.mu.Lock()
+	s.crawlDelay = delay
+	s.mu.Unlock()
+	s.signal()
+}
+
+// gate 暂停占用下载槽，直到对应的 ungate
+func (s *slot) gate() {
+	s.mu.Lock()
+	s.gated++
+	s.mu.Unlock()
+}
+
+// ungate 恢复占用下载槽
+func (s *slot) ungate() {
+	s.mu.Lock()
+	s.gated--
+	s.mu.Unlock()
+	s.signal()
+}
+
+// setLimit 设置最大并发数
+func (s *slot) setLimit(limit uint32) {
+	s.mu.Lock()
+	s.limit, s.maxLimit = limit, limit
+	s.mu.Unlock()
+	s.signal()
+}
+
+// rateToDelay 每秒请求数转换为令牌产生间隔
+func rateToDelay(rate float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / rate)
+}
@@ -0,0 +1,79 @@
+package gugo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlotRate(t *testing.T) {
+	sl := newSlot("example.com", time.Hour, 2, 0, nil)
+	for i := 0; i < 2; i++ {
+		if !sl.tryAcquire() {
+			t.Fatalf("tryAcquire %d = false, want burst of 2", i)
+		}
+	}
+	if sl.tryAcquire() {
+		t.Fatal("tryAcquire = true after the burst is used up")
+	}
+	if wait := sl.ready(); wait <= 0 {
+		t.Fatalf("ready = %s, want a positive wait", wait)
+	}
+}
+
+func TestSlotLimit(t *testing.T) {
+	sl := newSlot("example.com", 0, 1, 1, nil)
+	if !sl.tryAcquire() {
+		t.Fatal("tryAcquire = false, want true")
+	}
+	if sl.ready() >= 0 || sl.tryAcquire() {
+		t.Fatal("slot should be full")
+	}
+	sl.release()
+	if sl.ready() != 0 || !sl.tryAcquire() {
+		t.Fatal("slot should be free after release")
+	}
+}
+
+func TestSlotCrawlDelay(t *testing.T) {
+	sl := newSlot("example.com", 0, 4, 0, nil)
+	sl.setCrawlDelay(time.Hour)
+	if !sl.tryAcquire() {
+		t.Fatal("tryAcquire = false, want the first request to pass")
+	}
+	if sl.tryAcquire() {
+		t.Fatal("tryAcquire = true, Crawl-delay must not allow bursts")
+	}
+	sl.release()
+	// 自动限速和设置的限速都不能使下载间隔小于 Crawl-delay
+	sl.setRate(time.Millisecond, 4)
+	a := newAutoThrottle()
+	a.SetAutoThrottle(1)
+	a.feedback(sl, time.Millisecond, false)
+	if wait := sl.ready(); wait < 50*time.Minute {
+		t.Fatalf("ready = %s, want about Crawl-delay", wait)
+	}
+}
+
+func TestAutoThrottleBounds(t *testing.T) {
+	sl := newSlot("example.com", 0, 1, 0, nil)
+	sl.setRate(rateToDelay(2), 1)
+	sl.setLimit(2)
+	a := newAutoThrottle()
+	a.SetAutoThrottle(8)
+	for i := 0; i < 10; i++ {
+		a.feedback(sl, time.Millisecond, false)
+	}
+	if sl.delay != 500*time.Millisecond || sl.limit != 2 {
+		t.Fatalf("delay, limit = %s, %d, want 500ms, 2", sl.delay, sl.limit)
+	}
+	a.feedback(sl, 2*time.Second, true)
+	if sl.delay != 500*time.Millisecond || sl.limit != 1 {
+		t.Fatalf("after failure delay, limit = %s, %d, want 500ms, 1", sl.delay, sl.limit)
+	}
+	for i := 0; i < 10; i++ {
+		a.feedback(sl, 8*time.Second, false)
+	}
+	if sl.delay < time.Second || sl.limit != 2 {
+		t.Fatalf("slow host delay, limit = %s, %d, want at least 1s, 2", sl.delay, sl.limit)
+	}
+}
@@ -1,13 +1,22 @@
 package gugo
 
+import (
+	"log"
+	"sync"
+)
+
 const (
 	ResponseBufCap     = 1 << 12 // 默认响应队列容量
 	ConcurrentResponse = 1 << 10 // 默认响应处理的并发量
 )
 
 type spider struct {
-	resBuf             chan *Response // 响应队列
-	concurrentResponse chan struct{}  // 响应并发控制
+	resBuf             chan *Response     // 响应队列
+	concurrentResponse chan struct{}      // 响应并发控制
+	middlewares        []SpiderMiddleware // 爬虫中间件
+	pmu                sync.RWMutex
+	parsers            map[string]Parser // 已注册的解析器
+	names              map[string]string // 解析器函数名对应的注册名
 	*module
 }
 
@@ -15,16 +24,30 @@ func newSpider() *spider {
 	return &spider{
 		resBuf:             make(chan *Response, ResponseBufCap),
 		concurrentResponse: make(chan struct{}, ConcurrentResponse),
+		parsers:            make(map[string]Parser),
+		names:              make(map[string]string),
 		module:             &module{},
 	}
 }
 
-// parse 爬虫解析
+// response 爬虫解析，统计项：
+// 1、爬虫正在处理的数量
+// 2、响应被中间件拦截的数量
+// 3、响应解析完成的数量
 func (s *spider) response(res *Response) {
 	s.IncrHandlingNumber()
 	defer s.DecrHandlingNumber()
 	defer func() { <-s.concurrentResponse }()
+	if err := s.processSpiderInput(res); err != nil {
+		log.Printf("%s is intercepted by spider middleware: %v\n", res.URL(), err)
+		s.IncrInterceptCount()
+		res.discard()
+		return
+	}
 	res.parser(res)
+	// 解析器没有读完的响应体在解析后关闭，以便复用连接
+	res.discard()
+	s.IncrCompletedCount()
 }
 
 // SetResponseBufCap 设置响应队列容量
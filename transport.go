@@ -0,0 +1,161 @@
+package gugo
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	MaxIdleConnsPerHost = 16               // 默认每个域名保持的最大空闲连接数
+	IdleConnTimeout     = 90 * time.Second // 默认空闲连接的超时时间
+	TLSHandshakeTimeout = 10 * time.Second // 默认TLS握手超时时间
+)
+
+// newTransport 下载器共享的连接池，所有请求复用连接
+func newTransport(dialer *net.Dialer) *http.Transport {
+	return &http.Transport{
+		Proxy:               proxyFromContext,
+		DialContext:         dialer.DialContext,
+		MaxIdleConnsPerHost: MaxIdleConnsPerHost,
+		IdleConnTimeout:     IdleConnTimeout,
+		TLSHandshakeTimeout: TLSHandshakeTimeout,
+		ForceAttemptHTTP2:   true,
+	}
+}
+
+// Timeout 请求的读写超时时间，优先于下载器的读写超时时间
+func Timeout(timeout time.Duration) RequestOption {
+	return func(r *Request) {
+		r.timeout = timeout
+	}
+}
+
+// TotalTimeout 请求下载的总时间，优先于下载器的设置
+func TotalTimeout(timeout time.Duration) RequestOption {
+	return func(r *Request) {
+		r.downloadTimeout = timeout
+	}
+}
+
+// deadline 单个请求的超时：
+// 1、读写超时：等待响应头、每次读取响应体都不能超过读写超时时间，响应体等待解析期间不计时
+// 2、总超时：从发出请求到读完响应体不能超过总时间，通过请求上下文的截止时间实现
+// 任一超时后取消请求的上下文
+type deadline struct {
+	ctx     context.Context
+	timeout time.Duration
+	timer   *time.Timer // 读写超时计时器，nil表示不限制
+	cancel  context.CancelFunc
+	expired int32
+}
+
+// withDeadline 为请求设置读写超时和总超时，超时时间都为0时不设置
+func (d *downloader) withDeadline(req *Request, r *http.Request) (*http.Request, *deadline) {
+	timeout, total := d.readWriteTimeout, d.downloadTimeout
+	if req.timeout > 0 {
+		timeout = req.timeout
+	}
+	if req.downloadTimeout > 0 {
+		total = req.downloadTimeout
+	}
+	if timeout <= 0 && total <= 0 {
+		return r, nil
+	}
+	dl := &deadline{timeout: timeout}
+	if total > 0 {
+		dl.ctx, dl.cancel = context.WithTimeout(r.Context(), total)
+	} else {
+		dl.ctx, dl.cancel = context.WithCancel(r.Context())
+	}
+	if timeout > 0 {
+		dl.timer = time.AfterFunc(timeout, func() {
+			atomic.StoreInt32(&dl.expired, 1)
+			dl.cancel()
+		})
+	}
+	return r.WithContext(dl.ctx), dl
+}
+
+// timedOut 请求是否因为读写超时或总超时被取消
+func (dl *deadline) timedOut() bool {
+	return atomic.LoadInt32(&dl.expired) == 1 || dl.ctx.Err() == context.DeadlineExceeded
+}
+
+// stop 停止读写超时计时
+func (dl *deadline) stop() {
+	if dl.timer != nil {
+		dl.timer.Stop()
+	}
+}
+
+// done 请求结束，响应体交给 deadlineBody 继续计时
+func (dl *deadline) done(res *http.Response, err error) error {
+	if dl == nil {
+		return err
+	}
+	dl.stop()
+	if err != nil {
+		timedOut := dl.timedOut()
+		dl.cancel()
+		if timedOut {
+			return context.DeadlineExceeded
+		}
+		return err
+	}
+	res.Body = &deadlineBody{ReadCloser: res.Body, dl: dl}
+	return nil
+}
+
+// deadlineBody 每次读取都重新计时的响应体，总超时在读取期间继续有效
+type deadlineBody struct {
+	io.ReadCloser
+	dl *deadline
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	if b.dl.timer != nil {
+		b.dl.timer.Reset(b.dl.timeout)
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.dl.stop()
+	if err != nil && err != io.EOF && b.dl.timedOut() {
+		err = context.DeadlineExceeded
+	}
+	return n, err
+}
+
+func (b *deadlineBody) Close() error {
+	b.dl.stop()
+	b.dl.cancel()
+	return b.ReadCloser.Close()
+}
+
+// SetMaxIdleConnsPerHost 设置每个域名保持的最大空闲连接数
+func (d *downloader) SetMaxIdleConnsPerHost(n int) {
+	d.transport.MaxIdleConnsPerHost = n
+}
+
+// SetIdleConnTimeout 设置空闲连接的超时时间
+func (d *downloader) SetIdleConnTimeout(timeout time.Duration) {
+	d.transport.IdleConnTimeout = timeout
+}
+
+// SetTLSHandshakeTimeout 设置TLS握手超时时间
+func (d *downloader) SetTLSHandshakeTimeout(timeout time.Duration) {
+	d.transport.TLSHandshakeTimeout = timeout
+}
+
+// SetHTTP2 设置是否使用HTTP/2，默认使用
+func (d *downloader) SetHTTP2(enabled bool) {
+	d.transport.ForceAttemptHTTP2 = enabled
+	if enabled {
+		d.transport.TLSNextProto = nil
+	} else {
+		d.transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+}
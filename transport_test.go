@@ -0,0 +1,80 @@
+package gugo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// trickleHandler 立即返回响应头，之后每隔 interval 发送一个字节，共发送n个字节
+func trickleHandler(n int, interval time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < n; i++ {
+			_, _ = w.Write([]byte("x"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-time.After(interval):
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		trickleHandler(8, 30*time.Millisecond)(w, r)
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name  string
+		path  string
+		opts  []RequestOption
+		want  error // 读取完成后的错误
+		fetch error // 等待响应头时的错误
+	}{
+		{"idle timeout is reset by each read", "/", []RequestOption{Timeout(100 * time.Millisecond)}, nil, nil},
+		{"idle timeout waiting for headers", "/slow", []RequestOption{Timeout(50 * time.Millisecond)}, nil, ErrTimeout},
+		{"total timeout while reading", "/", []RequestOption{Timeout(100 * time.Millisecond), TotalTimeout(100 * time.Millisecond)}, context.DeadlineExceeded, nil},
+		{"total timeout waiting for headers", "/slow", []RequestOption{TotalTimeout(50 * time.Millisecond)}, nil, ErrTimeout},
+	}
+	d := newDownloader()
+	for _, tt := range tests {
+		r, _ := http.NewRequest(http.MethodGet, srv.URL+tt.path, nil)
+		start := time.Now()
+		res, err := d.do(NewRequest(r, nil, nil, tt.opts...), nil)
+		if tt.fetch != nil || err != nil {
+			if !errors.Is(err, tt.fetch) {
+				t.Errorf("%s: err = %v, want %v", tt.name, err, tt.fetch)
+			}
+			continue
+		}
+		body := res.Body()
+		if !errors.Is(res.BodyErr(), tt.want) {
+			t.Errorf("%s: body err = %v, want %v", tt.name, res.BodyErr(), tt.want)
+		}
+		if tt.want == nil && len(body) != 8 {
+			t.Errorf("%s: body = %q, want 8 bytes", tt.name, body)
+		}
+		if tt.want != nil && time.Since(start) > 200*time.Millisecond {
+			t.Errorf("%s: took %s, want the total timeout to stop reading", tt.name, time.Since(start))
+		}
+	}
+	// 下载器的总时间设置
+	d.SetDownloadTimeout(100 * time.Millisecond)
+	r, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	res, err := d.do(NewRequest(r, nil, nil), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Body(); !errors.Is(res.BodyErr(), context.DeadlineExceeded) {
+		t.Errorf("body err = %v, want the downloader total timeout", res.BodyErr())
+	}
+}